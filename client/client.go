@@ -8,8 +8,15 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/adamwoolhether/httper/client/download"
 	"github.com/adamwoolhether/httper/client/throttle"
@@ -19,8 +26,23 @@ import (
 // It sets a default *http.Client and *http.Transport, which
 // can be customized via optional funcs.
 type Client struct {
-	c      *http.Client
-	logger *slog.Logger
+	c              *http.Client
+	logger         *slog.Logger
+	deadlineBuffer *time.Duration
+	autoDecompress bool
+	baseURL        *url.URL
+	clientTrace    func(*http.Request) *httptrace.ClientTrace
+	maxAttempts    int
+	byteMetrics    ByteMetricsFunc
+	captureHeaders []string
+	throttle       throttle.Quiescer
+
+	// Background work started by Fire and DownloadAsync, cancelled by Close.
+	mu       sync.Mutex
+	closed   bool
+	closeCtx context.Context
+	closeFn  context.CancelFunc
+	bg       sync.WaitGroup
 }
 
 // Build constructs a new [Client] by applying the given options.
@@ -59,28 +81,83 @@ func Build(optFns ...Option) (*Client, error) {
 	default:
 		transport = http.DefaultTransport
 	}
+	transport, err := opts.tlsTransport(transport)
+	if err != nil {
+		return nil, fmt.Errorf("configuring tls: %w", err)
+	}
+	if transport, err = opts.intercept(PositionBase, transport); err != nil {
+		return nil, fmt.Errorf("configuring interceptors: %w", err)
+	}
 	if opts.userAgent != "" {
 		transport = userAgent{value: opts.userAgent, base: transport}
 	}
+	if transport, err = opts.intercept(PositionUserAgent, transport); err != nil {
+		return nil, fmt.Errorf("configuring interceptors: %w", err)
+	}
+	var quiescer throttle.Quiescer
 	if opts.throttle != nil {
 		rt, err := throttle.NewRoundTripper(opts.throttle.RPS, opts.throttle.Burst, func() *slog.Logger { return opts.logger }, transport)
 		if err != nil {
 			return nil, fmt.Errorf("configuring throttle: %w", err)
 		}
+		quiescer = rt.(throttle.Quiescer)
 		transport = rt
 	}
+	if opts.byteThrottle != nil {
+		rt, err := throttle.NewByteRoundTripper(opts.byteThrottle.BytesPerSec, opts.byteThrottle.Burst, transport)
+		if err != nil {
+			return nil, fmt.Errorf("configuring byte throttle: %w", err)
+		}
+		transport = rt
+	}
+	if transport, err = opts.intercept(PositionThrottle, transport); err != nil {
+		return nil, fmt.Errorf("configuring interceptors: %w", err)
+	}
+	if opts.maxTotalAttempts > 0 {
+		transport = attemptLimit{base: transport}
+	}
+	if opts.retryStatuses != nil && (opts.retry == nil || opts.retry.connErrorsOnly) {
+		return nil, errors.New("retry statuses need WithRetry")
+	}
+	if opts.retry != nil {
+		if !opts.retry.connErrorsOnly {
+			opts.retry.statuses = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+			if opts.retryStatuses != nil {
+				opts.retry.statuses = opts.retryStatuses
+			}
+		}
+		opts.retry.spoolMaxMem = opts.spoolMaxMem
+		opts.retry.base = transport
+		transport = opts.retry
+	}
+	if transport, err = opts.intercept(PositionOutermost, transport); err != nil {
+		return nil, fmt.Errorf("configuring interceptors: %w", err)
+	}
 
 	opts.client.Transport = transport
 
 	client := &Client{
-		c:      opts.client,
-		logger: opts.logger,
+		c:              opts.client,
+		logger:         opts.logger,
+		deadlineBuffer: opts.deadlineBuffer,
+		autoDecompress: opts.autoDecompress,
+		baseURL:        opts.baseURL,
+		clientTrace:    opts.clientTrace,
+		maxAttempts:    opts.maxTotalAttempts,
+		byteMetrics:    opts.byteMetrics,
+		captureHeaders: opts.captureHeaders,
+		throttle:       quiescer,
 	}
+	client.closeCtx, client.closeFn = context.WithCancel(context.Background())
 
 	return client, nil
 }
 
 // Do will fire the request, and write response to the given dest object if any.
+// The body is decoded as XML when the response Content-Type is an XML media
+// type and as JSON otherwise, unless overridden with [WithFormat]. A response
+// without a body, a 204, a 304 or one with a Content-Length of 0, isn't
+// decoded, leaving the destination of [WithDestination] untouched.
 func (c *Client) Do(req *http.Request, expCode int, opts ...DoOption) error {
 	var settings doOpts
 	for _, opt := range opts {
@@ -91,29 +168,259 @@ func (c *Client) Do(req *http.Request, expCode int, opts ...DoOption) error {
 	}
 
 	doFunc := func(resp *http.Response) error {
-		if settings.responseBody != nil {
-			d := json.NewDecoder(resp.Body)
+		var counter *countingReader
+		if settings.verifyContentLength && resp.ContentLength >= 0 {
+			counter = &countingReader{ReadCloser: resp.Body}
+			resp.Body = counter
+		}
 
-			if settings.useJSONNum {
-				d.UseNumber()
+		if settings.responseBody != nil && !emptyBody(resp) {
+			if err := decodeBody(resp, settings); err != nil {
+				return fmt.Errorf("decoding body: %w", err)
 			}
+		}
 
-			if err := d.Decode(settings.responseBody); err != nil {
-				return fmt.Errorf("decoding body: %w", err)
+		if counter != nil {
+			if err := verifyContentLength(counter, resp.ContentLength); err != nil {
+				return fmt.Errorf("verifying body: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	return c.exec(req, expCode, settings.hooks(c.captureHeaders), doFunc)
+}
+
+// DoMap fires the request and decodes the JSON response body into a fresh
+// map, preserving number precision as [json.Number]. It's a shorthand for
+// calling [Client.Do] with [WithDestination] and [WithJSONNumb], so a
+// response without a body gives an empty map.
+func (c *Client) DoMap(req *http.Request, expCode int) (map[string]any, error) {
+	m := map[string]any{}
+
+	if err := c.Do(req, expCode, WithDestination(&m), WithJSONNumb()); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// DoArray fires the request and streams the JSON array in the response body,
+// calling fn once per element as it's read, so large lists are processed in
+// bounded memory. fn is given decode, which decodes the current element into
+// a pointer as [json.Unmarshal] would; an element fn doesn't decode is
+// skipped. The first error from fn or from decoding stops the stream and is
+// returned. A response without a body, or a null one, has no elements. Of
+// the DoOptions, [WithJSONNumb] and those observing the request and response
+// apply.
+func (c *Client) DoArray(req *http.Request, expCode int, fn func(decode func(any) error) error, opts ...DoOption) error {
+	var settings doOpts
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	doFunc := func(resp *http.Response) error {
+		if emptyBody(resp) {
+			return nil
+		}
+
+		d := json.NewDecoder(resp.Body)
+		if settings.useJSONNum {
+			d.UseNumber()
+		}
+
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) || (err == nil && tok == nil) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("decoding array: %w", err)
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("decoding array: expected [, got %v", tok)
+		}
+
+		for i := 0; d.More(); i++ {
+			var decoded bool
+			decode := func(v any) error {
+				if decoded {
+					return errors.New("element already decoded")
+				}
+				decoded = true
+
+				return d.Decode(v)
+			}
+
+			if err := fn(decode); err != nil {
+				return fmt.Errorf("element[%d]: %w", i, err)
+			}
+
+			if !decoded {
+				var skip json.RawMessage
+				if err := d.Decode(&skip); err != nil {
+					return fmt.Errorf("element[%d]: %w", i, err)
+				}
+			}
+		}
+
+		if _, err := d.Token(); err != nil {
+			return fmt.Errorf("decoding array: %w", err)
+		}
+
+		return nil
+	}
+
+	return c.exec(req, expCode, settings.hooks(c.captureHeaders), doFunc)
+}
+
+// DoBatch fires the given requests concurrently, decoding each response into
+// its Dest as [Client.Do] would. Concurrency is unlimited unless capped with
+// [WithConcurrency]. Each request keeps its own context, e.g. with its own
+// timeout, and is also cancelled with ctx; once ctx is cancelled, requests
+// that haven't started fail with its error. Results are returned in
+// the same order as reqs.
+func (c *Client) DoBatch(ctx context.Context, reqs []BatchReq, optFns ...BatchOption) []BatchResult {
+	var opts batchOpts
+	for _, opt := range optFns {
+		if err := opt(&opts); err != nil {
+			results := make([]BatchResult, len(reqs))
+			for i, br := range reqs {
+				results[i] = BatchResult{Req: br.Req, Err: fmt.Errorf("applying option: %w", err)}
+			}
+			return results
+		}
+	}
+
+	var sem chan struct{}
+	if opts.maxConcurrent > 0 {
+		sem = make(chan struct{}, opts.maxConcurrent)
+	}
+
+	results := make([]BatchResult, len(reqs))
+	var wg sync.WaitGroup
+
+	for i, br := range reqs {
+		results[i].Req = br.Req
+
+		if br.Req == nil {
+			results[i].Err = errors.New("batch request must not be nil")
+			continue
+		}
+
+		wg.Go(func() {
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					results[i].Err = ctx.Err()
+					return
+				}
+			}
+
+			if err := ctx.Err(); err != nil {
+				results[i].Err = err
+				return
 			}
+
+			doOpts := br.Opts
+			if br.Dest != nil {
+				doOpts = append([]DoOption{withResponseBody(br.Dest)}, doOpts...)
+			}
+
+			reqCtx, cancel := withCancelFrom(br.Req.Context(), ctx)
+			defer cancel()
+
+			results[i].Err = c.Do(br.Req.WithContext(reqCtx), br.ExpCode, doOpts...)
+		})
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// DoResult fires the request and decodes the JSON response body into S when
+// the status matches expCode. On any other status the error body is decoded
+// whole into E and returned alongside the [UnexpectedStatusError], so APIs
+// with distinct success and error schemas can be handled in one call. An
+// empty error body returns the status error alone, with a nil E.
+func DoResult[S, E any](c *Client, req *http.Request, expCode int) (*S, *E, error) {
+	var success S
+	var failure *E
+
+	decodeFailure := func(body io.Reader) error {
+		var f E
+		if err := json.NewDecoder(body).Decode(&f); err != nil {
+			return fmt.Errorf("decoding error body: %w", err)
 		}
+		failure = &f
 
 		return nil
 	}
 
-	return c.exec(req, expCode, doFunc)
+	if err := c.Do(req, expCode, WithDestination(&success), withErrorBody(decodeFailure)); err != nil {
+		return nil, failure, err
+	}
+
+	return &success, nil, nil
+}
+
+// Fire sends the request asynchronously as [Client.Do] would, for best-effort
+// side effects such as telemetry pings. The request is cloned onto a context
+// detached from the caller's cancellation, keeping its values, and bounded
+// by a 30s timeout, so it outlives the handler that fired it. Errors are
+// logged rather than returned.
+func (c *Client) Fire(req *http.Request, expCode int, opts ...DoOption) {
+	if !c.track() {
+		c.logger.Error("fire and forget", "method", req.Method, "url", req.URL.String(), "error", ErrClientClosed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), fireTimeout)
+	stop := context.AfterFunc(c.closeCtx, cancel)
+	detached := req.Clone(ctx)
+
+	go func() {
+		defer c.bg.Done()
+		defer stop()
+		defer cancel()
+
+		if err := c.Do(detached, expCode, opts...); err != nil {
+			c.logger.Error("fire and forget", "method", detached.Method, "url", detached.URL.String(), "error", err)
+		}
+	}()
 }
 
 // Download executes a request that's intended to stream the response body it to destPath.
 // Data streams to a temp file in the same directory, then the temp file is renamed to
 // destPath on success or cleared on failure. Cancellation of an in-progress download can
-// be done with a custom context injected into the *http.Request.
+// be done with a custom context injected into the *http.Request. With [download.WithResume],
+// a partial file left by an earlier attempt is resumed with a Range request, accepting
+// 206 Partial Content in place of expCode. With [download.WithParallel], a HEAD request
+// first checks the server supports ranges, falling back to a single request if not.
 func (c *Client) Download(req *http.Request, expCode int, destPath string, optFns ...download.Option) error {
+	return c.download(req, expCode, nil, destPath, optFns)
+}
+
+// DownloadAny is [Client.Download] accepting any of expCodes as success,
+// e.g. []int{200, 206} for a server that may answer a plain request with
+// a range covering the whole file. A 206 response must start at the first
+// byte, or at the offset resumed under [download.WithResume].
+func (c *Client) DownloadAny(req *http.Request, expCodes []int, destPath string, optFns ...download.Option) error {
+	if len(expCodes) == 0 {
+		return errors.New("expCodes must not be empty")
+	}
+
+	return c.download(req, expCodes[0], expCodes[1:], destPath, optFns)
+}
+
+// download is [Client.Download], also accepting the statuses in accept.
+func (c *Client) download(req *http.Request, expCode int, accept []int, destPath string, optFns []download.Option) error {
 	if destPath == "" {
 		return errors.New("destPath must not be empty")
 	}
@@ -125,15 +432,73 @@ func (c *Client) Download(req *http.Request, expCode int, destPath string, optFn
 		}
 	}
 
+	if opts.Parallel() > 1 {
+		if size, ok := c.rangeSize(req, expCode, accept); ok {
+			if err := download.HandleParallel(req.Context(), size, destPath, c.logger, opts, c.fetchRange(req, opts)); err != nil {
+				return fmt.Errorf("download: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	req, hooks, offset := resumeRequest(req, destPath, opts)
+	hooks.accept = accept
+
+	dlFunc := func(resp *http.Response) error {
+		if err := opts.CheckContentType(resp.Header.Get("Content-Type")); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+
+		start, err := rangeStart(resp, offset)
+		if err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+
+		path, err := opts.ResolvePath(destPath, resp.Header.Get("Content-Disposition"), responseURL(resp))
+		if err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+
+		if err := download.HandleAt(req.Context(), resp.Body, resp.ContentLength, start, path, c.logger, opts); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+
+		return nil
+	}
+
+	return c.exec(req, expCode, hooks, dlFunc)
+}
+
+// DownloadTo executes a request and streams the response body to w, with the same status
+// check, decompression, progress and checksum verification as [Client.Download], but
+// without a temp file. w may have received part of the body when an error is returned.
+// Options that need a file, such as [download.WithResume], are rejected.
+func (c *Client) DownloadTo(req *http.Request, expCode int, w io.Writer, optFns ...download.Option) error {
+	if w == nil {
+		return errors.New("writer must not be nil")
+	}
+
+	var opts download.Options
+	for _, opt := range optFns {
+		if err := opt(&opts); err != nil {
+			return fmt.Errorf("applying option: %w", err)
+		}
+	}
+
 	dlFunc := func(resp *http.Response) error {
-		if err := download.Handle(req.Context(), resp.Body, resp.ContentLength, destPath, c.logger, opts); err != nil {
+		if err := opts.CheckContentType(resp.Header.Get("Content-Type")); err != nil {
+			return fmt.Errorf("download: %w", err)
+		}
+
+		if err := download.HandleTo(req.Context(), resp.Body, resp.ContentLength, w, c.logger, opts); err != nil {
 			return fmt.Errorf("download: %w", err)
 		}
 
 		return nil
 	}
 
-	return c.exec(req, expCode, dlFunc)
+	return c.exec(req, expCode, execHooks{}, dlFunc)
 }
 
 // DownloadAsync starts an asynchronous download managed by a queue.
@@ -160,23 +525,339 @@ func (c *Client) DownloadAsync(req *http.Request, expCode int, destPath string,
 	queue := opts.Group
 
 	fn := func(ctx context.Context) error {
-		req = req.WithContext(ctx)
+		if opts.Parallel() > 1 {
+			if size, ok := c.rangeSize(req.WithContext(ctx), expCode, nil); ok {
+				opts, release, err := opts.ReserveDisk(ctx, size)
+				if err != nil {
+					return err
+				}
+				defer release()
+
+				return download.HandleParallel(ctx, size, destPath, c.logger, opts, c.fetchRange(req, opts))
+			}
+		}
+
+		// Reserved before the request is sent, so a download waiting
+		// on the disk budget doesn't hold a connection open.
+		size := int64(-1)
+		if opts.DiskBudget() {
+			size = c.headSize(req.WithContext(ctx), expCode)
+		}
+		opts, release, err := opts.ReserveDisk(ctx, size)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		req, hooks, offset := resumeRequest(req.WithContext(ctx), destPath, opts)
 
 		dlFunc := func(resp *http.Response) error {
-			return download.Handle(ctx, resp.Body, resp.ContentLength, destPath, c.logger, opts)
+			if err := opts.CheckContentType(resp.Header.Get("Content-Type")); err != nil {
+				return err
+			}
+
+			start, err := rangeStart(resp, offset)
+			if err != nil {
+				return err
+			}
+
+			path, err := opts.ResolvePath(destPath, resp.Header.Get("Content-Disposition"), responseURL(resp))
+			if err != nil {
+				return err
+			}
+
+			return download.HandleAt(ctx, resp.Body, resp.ContentLength, start, path, c.logger, opts)
 		}
 
-		return c.exec(req, expCode, dlFunc)
+		return c.exec(req, expCode, hooks, dlFunc)
+	}
+
+	if !c.track() {
+		return nil, ErrClientClosed
 	}
 
 	r := queue.Start(req.Context(), fn, c.DownloadAsync)
+	stop := context.AfterFunc(c.closeCtx, r.Cancel)
+
+	go func() {
+		defer c.bg.Done()
+		<-r.Done()
+		stop()
+	}()
 
 	return r, nil
 }
 
+// Close cancels the requests sent by [Client.Fire] and the downloads started
+// by [Client.DownloadAsync] that are still in flight, waits for them to
+// return, then closes idle connections. Synchronous calls such as
+// [Client.Do] are unaffected, as they're bound to their request's context.
+// Starting background work after Close fails with [ErrClientClosed]. Close
+// first calls [Client.Quiesce], so requests of any kind still waiting on the
+// throttle fail fast. Close always returns nil, implementing [io.Closer] so
+// it can be registered with a server's shutdown, and is safe to call more
+// than once.
+func (c *Client) Close() error {
+	c.Quiesce()
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.closeFn()
+	c.bg.Wait()
+	c.c.CloseIdleConnections()
+
+	return nil
+}
+
+// Quiesce stops the throttle of [WithThrottle] admitting requests, so
+// those waiting for a token and any sent later fail fast with
+// [throttle.ErrShuttingDown], while requests already past it complete.
+// Without a throttle it does nothing. It's safe to call more than once.
+func (c *Client) Quiesce() {
+	if c.throttle != nil {
+		c.throttle.Quiesce()
+	}
+}
+
+// track registers background work to be cancelled and awaited by
+// [Client.Close], reporting false if the client is already closed.
+// The caller must call c.bg.Done once the work returns.
+func (c *Client) track() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	c.bg.Add(1)
+
+	return true
+}
+
+// resumeRequest returns req with a Range header asking for the bytes after
+// those already in the partial file for destPath under [download.WithResume],
+// along with the offset requested. Without a partial file, req is returned
+// unchanged with an offset of 0.
+func resumeRequest(req *http.Request, destPath string, opts download.Options) (*http.Request, execHooks, int64) {
+	offset := opts.ResumeOffset(destPath)
+	if offset == 0 {
+		return req, execHooks{}, 0
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+	return req, execHooks{acceptPartial: true}, offset
+}
+
+// rangeStart returns the offset the response body starts at in the file.
+// A 206 Partial Content response must start at the offset requested, any
+// other response holds the whole file.
+func rangeStart(resp *http.Response, offset int64) (int64, error) {
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, nil
+	}
+
+	var start, end int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/", &start, &end); err != nil {
+		return 0, fmt.Errorf("parsing Content-Range %q: %w", resp.Header.Get("Content-Range"), err)
+	}
+	if start != offset {
+		return 0, fmt.Errorf("requested range from %d, got %d", offset, start)
+	}
+
+	return start, nil
+}
+
+// responseURL returns the URL resp was fetched from, after any redirects.
+func responseURL(resp *http.Response) *url.URL {
+	if resp.Request == nil {
+		return nil
+	}
+
+	return resp.Request.URL
+}
+
+// rangeSize sends a HEAD request for req, reporting the size of the file
+// if the server answers with expCode or one of accept, "Accept-Ranges: bytes"
+// and a known Content-Length, so it can be fetched in ranges by
+// [download.HandleParallel].
+func (c *Client) rangeSize(req *http.Request, expCode int, accept []int) (int64, bool) {
+	head := req.Clone(req.Context())
+	head.Method = http.MethodHead
+	head.Body = http.NoBody
+	head.ContentLength = 0
+
+	var size int64
+	headFunc := func(resp *http.Response) error {
+		if resp.Header.Get("Accept-Ranges") == "bytes" && resp.ContentLength > 0 {
+			size = resp.ContentLength
+		}
+
+		return nil
+	}
+
+	if err := c.exec(head, expCode, execHooks{accept: accept}, headFunc); err != nil {
+		c.logger.Debug("range probe failed, downloading sequentially", "url", req.URL.String(), "error", err)
+		return 0, false
+	}
+
+	return size, size > 0
+}
+
+// headSize sends a HEAD request for req, returning the Content-Length of
+// the response, or -1 if it's unknown or the request fails.
+func (c *Client) headSize(req *http.Request, expCode int) int64 {
+	head := req.Clone(req.Context())
+	head.Method = http.MethodHead
+	head.Body = http.NoBody
+	head.ContentLength = 0
+
+	size := int64(-1)
+	headFunc := func(resp *http.Response) error {
+		size = resp.ContentLength
+		return nil
+	}
+
+	if err := c.exec(head, expCode, execHooks{}, headFunc); err != nil {
+		c.logger.Debug("size probe failed, reserving the whole disk budget", "url", req.URL.String(), "error", err)
+		return -1
+	}
+
+	return size
+}
+
+// fetchRange returns a [download.FetchRangeFunc] requesting byte ranges
+// of req, each of which must be answered with 206 Partial Content.
+func (c *Client) fetchRange(req *http.Request, opts download.Options) download.FetchRangeFunc {
+	return func(ctx context.Context, start, end int64, fn func(body io.Reader) error) error {
+		chunk := req.Clone(ctx)
+		chunk.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+		chunkFunc := func(resp *http.Response) error {
+			if err := opts.CheckContentType(resp.Header.Get("Content-Type")); err != nil {
+				return err
+			}
+
+			if _, err := rangeStart(resp, start); err != nil {
+				return err
+			}
+
+			return fn(resp.Body)
+		}
+
+		return c.exec(chunk, http.StatusPartialContent, execHooks{}, chunkFunc)
+	}
+}
+
+// ListDirectory fetches an index-style directory listing and parses it into
+// entries that can be fed into [Client.Download] or [Client.DownloadAsync].
+// Apache/nginx autoindex HTML and nginx's JSON autoindex format are parsed
+// by default; use [WithListingParser] for other formats.
+func (c *Client) ListDirectory(req *http.Request, expCode int, optFns ...ListOption) ([]download.DirEntry, error) {
+	opts := listOpts{parser: download.ParseAutoindex}
+	for _, opt := range optFns {
+		if err := opt(&opts); err != nil {
+			return nil, fmt.Errorf("applying option: %w", err)
+		}
+	}
+
+	var entries []download.DirEntry
+
+	listFunc := func(resp *http.Response) error {
+		var err error
+		entries, err = opts.parser(resp.Request.URL, resp.Header.Get("Content-Type"), resp.Body)
+		if err != nil {
+			return fmt.Errorf("parsing listing: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := c.exec(req, expCode, execHooks{}, listFunc); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// LongPoll repeatedly sends the request produced by buildReq until ctx is
+// cancelled. Each request keeps its own context, e.g. with a per-poll
+// deadline, and is also cancelled with ctx. A 200 response body is passed
+// to onUpdate before reconnecting; a 204 response or a timeout reconnects
+// immediately. Any other failure is
+// logged and retried after an exponential backoff bounded by opts. LongPoll
+// returns ctx's error on cancellation, or the first error from buildReq or
+// onUpdate.
+func (c *Client) LongPoll(ctx context.Context, buildReq func() (*http.Request, error), onUpdate func([]byte) error, opts LongPollOptions) error {
+	if buildReq == nil || onUpdate == nil {
+		return errors.New("buildReq and onUpdate must not be nil")
+	}
+
+	opts = opts.withDefaults()
+	backoff := opts.MinBackoff
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		req, err := buildReq()
+		if err != nil {
+			return fmt.Errorf("building long poll request: %w", err)
+		}
+
+		var updateErr error
+		pollFunc := func(resp *http.Response) error {
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return fmt.Errorf("reading body: %w", err)
+			}
+
+			updateErr = onUpdate(data)
+
+			return nil
+		}
+
+		pollCtx, cancel := withCancelFrom(req.Context(), ctx)
+		err = c.exec(req.WithContext(pollCtx), http.StatusOK, execHooks{}, pollFunc)
+		cancel()
+
+		switch {
+		case updateErr != nil:
+			return fmt.Errorf("long poll update: %w", updateErr)
+
+		case err == nil || isNoContent(err) || isTimeout(ctx, err):
+			backoff = opts.MinBackoff
+			continue
+
+		case ctx.Err() != nil:
+			return ctx.Err()
+		}
+
+		c.logger.Warn("long poll failed, backing off", "error", err, "backoff", backoff.String())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, opts.MaxBackoff)
+	}
+}
+
 // Request instantiates an *http.Request with the provided information.
 // It's just a convenience method that wraps the public Request func.
+// With [WithBaseURL], a reqURL without a scheme and host, e.g. from
+// c.URL("", "", "/v1/users"), is joined onto the base URL.
 func (c *Client) Request(ctx context.Context, reqURL *url.URL, method string, opts ...RequestOption) (*http.Request, error) {
+	if c.baseURL != nil {
+		reqURL = resolveURL(c.baseURL, reqURL)
+	}
+
 	return Request(ctx, reqURL, method, opts...)
 }
 
@@ -191,12 +872,62 @@ func (c *Client) InternalClient() *http.Client {
 }
 
 // exec runs the request and injected function on success after validating the expected status code.
-func (c *Client) exec(req *http.Request, expCode int, fn execFn) error {
+// The request's deadline is shortened first if [WithDeadlinePropagation] is set, and the trace of
+// [WithClientTrace] attached.
+// Any non-nil hooks are run around sending the request, see [execHooks].
+func (c *Client) exec(req *http.Request, expCode int, hooks execHooks, fn execFn) error {
+	if c.deadlineBuffer != nil {
+		if deadline, ok := req.Context().Deadline(); ok {
+			ctx, cancel := context.WithDeadline(req.Context(), deadline.Add(-*c.deadlineBuffer))
+			defer cancel()
+
+			req = req.WithContext(ctx)
+		}
+	}
+
+	if c.maxAttempts > 0 {
+		req = req.WithContext(context.WithValue(req.Context(), attemptsKey{}, &attemptBudget{max: int64(c.maxAttempts)}))
+	}
+
+	if c.clientTrace != nil {
+		if trace := c.clientTrace(req); trace != nil {
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+		}
+	}
+	if hooks.timings != nil {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), hooks.timings.trace()))
+	}
+
+	if hooks.beforeSend != nil {
+		if err := hooks.beforeSend(req); err != nil {
+			return fmt.Errorf("exec before send: %w", err)
+		}
+	}
+
+	var sent, received atomic.Int64
+	if c.byteMetrics != nil {
+		req = countRequestBody(req, &sent)
+		defer func() {
+			c.byteMetrics(req, sent.Load(), received.Load())
+		}()
+	}
+
 	resp, err := c.c.Do(req)
 	if err != nil {
+		if _, ok := errors.AsType[*net.DNSError](err); ok {
+			return fmt.Errorf("exec http do: %w: %w", ErrDNSResolution, err)
+		}
 		return fmt.Errorf("exec http do: %w", err)
 	}
 
+	if c.byteMetrics != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, n: &received}
+	}
+
+	if c.autoDecompress {
+		decompressBody(resp)
+	}
+
 	discardBody := true
 	defer func() {
 		if discardBody {
@@ -209,10 +940,16 @@ func (c *Client) exec(req *http.Request, expCode int, fn execFn) error {
 		}
 	}()
 
-	if resp.StatusCode != expCode {
-		b, err := io.ReadAll(io.LimitReader(resp.Body, maxErrBodySize))
-		if err != nil {
-			b = []byte("unable to read body")
+	if hooks.onResponse != nil {
+		hooks.onResponse(resp)
+	}
+
+	if resp.StatusCode != expCode && !slices.Contains(hooks.accept, resp.StatusCode) &&
+		!(hooks.acceptPartial && resp.StatusCode == http.StatusPartialContent) {
+		b, readErr := io.ReadAll(io.LimitReader(resp.Body, maxErrBodySize))
+		body := b
+		if readErr != nil {
+			body = []byte("unable to read body")
 		}
 
 		retErr := ErrUnexpectedStatusCode
@@ -220,11 +957,20 @@ func (c *Client) exec(req *http.Request, expCode int, fn execFn) error {
 			retErr = errors.Join(retErr, ErrAuthFailure)
 		}
 
-		return &UnexpectedStatusError{
+		statusErr := &UnexpectedStatusError{
 			StatusCode: resp.StatusCode,
-			Body:       string(b),
+			Body:       string(body),
 			Err:        retErr,
 		}
+
+		// The hook reads the whole body, the captured prefix followed by the rest.
+		if hooks.errorBody != nil && len(b) > 0 {
+			if err := hooks.errorBody(io.MultiReader(bytes.NewReader(b), resp.Body)); err != nil {
+				return fmt.Errorf("%w: %w", statusErr, err)
+			}
+		}
+
+		return statusErr
 	}
 
 	if err := fn(resp); err != nil {
@@ -246,36 +992,92 @@ func Request(ctx context.Context, reqURL *url.URL, method string, opts ...Reques
 		}
 	}
 
+	var bodies int
+	for _, set := range []bool{settings.body != nil, settings.form != nil, settings.multipart != nil, settings.file != ""} {
+		if set {
+			bodies++
+		}
+	}
+	if bodies > 1 {
+		return nil, errors.New("cannot use more than one of payload, form payload, multipart and file upload")
+	}
+
 	var payload bytes.Buffer
-	if settings.body != nil {
+	var body io.Reader = &payload
+	var mpw *multipart.Writer
+	var pw *io.PipeWriter
+	var upload *fileUpload
+	switch {
+	case settings.body != nil:
 		if err := json.NewEncoder(&payload).Encode(settings.body); err != nil {
 			return nil, fmt.Errorf("encoding request payload: %w", err)
 		}
+	case settings.form != nil:
+		payload.WriteString(settings.form.Encode())
+	case settings.multipart != nil:
+		var pr *io.PipeReader
+		pr, pw = io.Pipe()
+		mpw = multipart.NewWriter(pw)
+		body = pr
+	case settings.file != "":
+		var err error
+		if upload, err = openFileUpload(settings.file); err != nil {
+			return nil, err
+		}
+		body = upload.file
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), &payload)
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), body)
 	if err != nil {
+		if upload != nil {
+			upload.file.Close()
+		}
 		return nil, fmt.Errorf("instantiating request: %w", err)
 	}
 
+	if upload != nil {
+		req.ContentLength = upload.size
+		req.GetBody = upload.reopen
+	}
+
 	for _, cookie := range settings.cookies {
 		req.AddCookie(cookie)
 	}
 
 	var contentType string
-	if settings.contentType == nil {
-		contentType = "application/json"
-	} else {
+	switch {
+	case settings.contentType != nil:
 		contentType = *settings.contentType
+	case settings.form != nil:
+		contentType = "application/x-www-form-urlencoded"
+	case mpw != nil:
+		contentType = mpw.FormDataContentType()
+	case upload != nil:
+		contentType = upload.contentType
+	default:
+		contentType = "application/json"
 	}
 
 	req.Header.Set("Content-Type", contentType)
+	if settings.accept != "" {
+		req.Header.Set("Accept", settings.accept)
+	}
 	for k, v := range settings.headers {
 		for _, element := range v {
 			req.Header.Add(k, element)
 		}
 	}
 
+	if settings.uploadProgress != nil {
+		withUploadProgress(req, settings.uploadProgress)
+	}
+
+	if mpw != nil {
+		go func() {
+			pw.CloseWithError(settings.multipart.write(mpw))
+		}()
+	}
+
 	return req, nil
 }
 
@@ -305,5 +1107,39 @@ func URL(scheme, host, path string, opts ...URLOption) *url.URL {
 		endpoint.RawQuery = queryParams.Encode()
 	}
 
+	if settings.rawQuery != nil {
+		endpoint.RawQuery = *settings.rawQuery
+	}
+
 	return &endpoint
 }
+
+// JoinPath returns a copy of base with segments appended to its path, one
+// path segment each, as with [url.JoinPath] but escaping every segment, so
+// a dynamic value such as an ID or name can't add separators or climb with
+// "..". Slashes between base and the segments are normalized, and base's
+// scheme, host, query and fragment are kept.
+func JoinPath(base *url.URL, segments ...string) *url.URL {
+	escaped := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		switch seg {
+		case "":
+			continue
+		case ".":
+			seg = "%2E"
+		case "..":
+			seg = "%2E%2E"
+		default:
+			seg = url.PathEscape(seg)
+		}
+		escaped = append(escaped, seg)
+	}
+
+	// An empty path joins without a leading slash, so root it at the host.
+	u := *base
+	if u.Host != "" && u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.JoinPath(escaped...)
+}
@@ -1,19 +1,48 @@
 package download
 
 import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"hash"
+	"strings"
 )
 
-// checksumVerifier enables checksum validation of the downloaded file.
+// checksumVerifier enables checksum validation of the downloaded file
+// against one or more expected checksums, any of which may match.
 type checksumVerifier struct {
-	hash     hash.Hash
-	expected string
+	checksums []checksum
+}
+
+// checksum is a single expected checksum. algorithm is empty when the
+// hash was supplied directly via [WithChecksum].
+type checksum struct {
+	algorithm string
+	hash      hash.Hash
+	expected  string // Hex-encoded.
+}
+
+// add registers another expected checksum, creating the verifier if v is nil.
+func (v *checksumVerifier) add(c checksum) *checksumVerifier {
+	if v == nil {
+		v = &checksumVerifier{}
+	}
+	v.checksums = append(v.checksums, c)
+
+	return v
 }
 
 func (v *checksumVerifier) Write(p []byte) (int, error) {
-	return v.hash.Write(p)
+	for _, c := range v.checksums {
+		c.hash.Write(p) // hash.Hash never returns an error.
+	}
+
+	return len(p), nil
 }
 
 func (v *checksumVerifier) Verify() error {
@@ -21,13 +50,99 @@ func (v *checksumVerifier) Verify() error {
 		return nil
 	}
 
-	actual := hex.EncodeToString(v.hash.Sum(nil))
-	if actual != v.expected {
-		return &Error{
-			Err:    ErrChecksumMismatch,
-			Detail: fmt.Sprintf("expected %s, got %s", v.expected, actual),
+	details := make([]string, len(v.checksums))
+	for i, c := range v.checksums {
+		actual := hex.EncodeToString(c.hash.Sum(nil))
+		if actual == c.expected {
+			return nil
+		}
+
+		details[i] = fmt.Sprintf("expected %s, got %s", c.expected, actual)
+		if c.algorithm != "" {
+			details[i] = c.algorithm + ": " + details[i]
+		}
+	}
+
+	return &Error{
+		Err:    ErrChecksumMismatch,
+		Detail: strings.Join(details, "; "),
+	}
+}
+
+// checksumAlgorithms maps the algorithm prefixes accepted by
+// [WithChecksumString] to their hash constructors.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// algorithmsBySize maps digest sizes to the algorithm inferred when
+// [WithChecksumString] is given no prefix.
+var algorithmsBySize = map[int]string{
+	md5.Size:       "md5",
+	sha1.Size:      "sha1",
+	sha256.Size:    "sha256",
+	sha512.Size384: "sha384",
+	sha512.Size:    "sha512",
+}
+
+// parseChecksum parses an "<algorithm>:<digest>" string, with the digest
+// hex or base64 encoded. Without a prefix, the algorithm is inferred from
+// the digest's size.
+func parseChecksum(s string) (checksum, error) {
+	algorithm, digest, prefixed := strings.Cut(strings.TrimSpace(s), ":")
+	if !prefixed {
+		algorithm, digest = "", algorithm
+	}
+	algorithm = strings.ToLower(strings.ReplaceAll(algorithm, "-", ""))
+
+	validSize := func(n int) bool {
+		_, ok := algorithmsBySize[n]
+		return ok
+	}
+	if prefixed {
+		newHash, ok := checksumAlgorithms[algorithm]
+		if !ok {
+			return checksum{}, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+		}
+		size := newHash().Size()
+		validSize = func(n int) bool { return n == size }
+	}
+
+	sum, err := decodeDigest(digest, validSize)
+	if err != nil {
+		return checksum{}, err
+	}
+	if !prefixed {
+		algorithm = algorithmsBySize[len(sum)]
+	}
+
+	return checksum{algorithm: algorithm, hash: checksumAlgorithms[algorithm](), expected: hex.EncodeToString(sum)}, nil
+}
+
+// decodeDigest decodes a hex digest, falling back to standard or URL-safe
+// base64, padded or not. The first decoding for which validSize reports
+// true is returned, as short base64 digests may also be valid hex.
+func decodeDigest(digest string, validSize func(n int) bool) ([]byte, error) {
+	if digest == "" {
+		return nil, errors.New("checksum must not be empty")
+	}
+
+	decoders := []func(string) ([]byte, error){
+		hex.DecodeString,
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+	}
+	for _, decode := range decoders {
+		if sum, err := decode(digest); err == nil && validSize(len(sum)) {
+			return sum, nil
 		}
 	}
 
-	return nil
+	return nil, fmt.Errorf("checksum %q is not a hex or base64 digest of a supported size", digest)
 }
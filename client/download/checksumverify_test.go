@@ -0,0 +1,108 @@
+package download
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksum(t *testing.T) {
+	data := []byte("checksum test data")
+	sha := sha256.Sum256(data)
+	md := md5.Sum(data)
+	shaHex := hex.EncodeToString(sha[:])
+	mdHex := hex.EncodeToString(md[:])
+
+	tests := []struct {
+		name      string
+		in        string
+		algorithm string
+		wantErr   bool
+	}{
+		{name: "prefixed hex", in: "sha256:" + shaHex, algorithm: "sha256"},
+		{name: "prefixed upper hex", in: "SHA-256:" + strings.ToUpper(shaHex), algorithm: "sha256"},
+		{name: "prefixed base64", in: "sha256:" + base64.StdEncoding.EncodeToString(sha[:]), algorithm: "sha256"},
+		{name: "prefixed raw url base64", in: "sha256:" + base64.RawURLEncoding.EncodeToString(sha[:]), algorithm: "sha256"},
+		{name: "md5 base64", in: "md5:" + base64.StdEncoding.EncodeToString(md[:]), algorithm: "md5"},
+		{name: "inferred sha256", in: shaHex, algorithm: "sha256"},
+		{name: "inferred md5", in: mdHex, algorithm: "md5"},
+		{name: "unsupported algorithm", in: "crc32:" + shaHex, wantErr: true},
+		{name: "wrong size for algorithm", in: "sha256:" + mdHex, wantErr: true},
+		{name: "unknown size", in: "abcd", wantErr: true},
+		{name: "not encoded", in: "sha256:not a digest!", wantErr: true},
+		{name: "empty digest", in: "sha256:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChecksum(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got.algorithm != tt.algorithm {
+				t.Errorf("algorithm = %q, want %q", got.algorithm, tt.algorithm)
+			}
+
+			v := (*checksumVerifier)(nil).add(got)
+			v.Write(data)
+			if err := v.Verify(); err != nil {
+				t.Errorf("verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestChecksumVerifier_AnyMatch(t *testing.T) {
+	data := []byte("checksum test data")
+	sha := sha256.Sum256(data)
+
+	wrong, err := parseChecksum("md5:" + strings.Repeat("0", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	right, err := parseChecksum("sha256:" + hex.EncodeToString(sha[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := (*checksumVerifier)(nil).add(wrong).add(right)
+	v.Write(data)
+	if err := v.Verify(); err != nil {
+		t.Errorf("expected a match, got %v", err)
+	}
+}
+
+func TestChecksumVerifier_MismatchNamesAlgorithms(t *testing.T) {
+	md, err := parseChecksum("md5:" + strings.Repeat("0", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha, err := parseChecksum("sha256:" + strings.Repeat("0", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := (*checksumVerifier)(nil).add(md).add(sha)
+	v.Write([]byte("checksum test data"))
+
+	err = v.Verify()
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	for _, algorithm := range []string{"md5: expected", "sha256: expected"} {
+		if !strings.Contains(err.Error(), algorithm) {
+			t.Errorf("expected %q in %q", algorithm, err)
+		}
+	}
+}
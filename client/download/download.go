@@ -1,6 +1,7 @@
 package download
 
 import (
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
@@ -8,12 +9,30 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
-	"time"
 )
 
 // Handle streams body to a temp file in the same directory as destPath, then renames it
 // on success. On any error the temp file is removed.
 func Handle(ctx context.Context, body io.Reader, contentLength int64, destPath string, logger *slog.Logger, opts Options) error {
+	return HandleAt(ctx, body, contentLength, 0, destPath, logger, opts)
+}
+
+// HandleAt is [Handle] for a body holding the file from byte offset onwards,
+// as returned for a Range request. A non-zero offset requires [WithResume],
+// and is appended to the partial file, which must hold exactly offset bytes.
+// With [WithResume], the partial file is kept when the download fails, so a
+// later call can resume it, unless the checksum doesn't match.
+func HandleAt(ctx context.Context, body io.Reader, contentLength, offset int64, destPath string, logger *slog.Logger, opts Options) error {
+	if offset > 0 && !opts.resume {
+		return errors.New("offset requires WithResume")
+	}
+	if opts.resume && opts.decompress {
+		return errors.New("WithResume cannot be used with WithDecompress")
+	}
+	if opts.resume && opts.filenameFromHeader {
+		return errors.New("WithResume cannot be used with WithFilenameFromHeader")
+	}
+
 	if opts.skipExisting {
 		if _, err := os.Stat(destPath); err == nil {
 			logger.Info("skipping existing file", "path", destPath)
@@ -21,70 +40,181 @@ func Handle(ctx context.Context, body io.Reader, contentLength int64, destPath s
 		}
 	}
 
-	body = &contextReader{ctx: ctx, r: body}
-
-	file, err := os.CreateTemp(filepath.Dir(destPath), ".httper-dl-*")
+	file, err := openTemp(destPath, offset, opts.resume)
 	if err != nil {
-		return fmt.Errorf("creating temp file: %w", err)
+		return err
 	}
 
 	var successful bool
+	keepPartial := opts.resume
 	defer func() {
 		if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
 			logger.Error("defer closing temp file", "error", err)
 		}
-		if !successful {
+		if !successful && !keepPartial {
 			if err := os.Remove(file.Name()); err != nil {
 				logger.Error("failed to remove temp file", "error", err)
 			}
 		}
 	}()
 
+	// The checksum covers the whole file, including the resumed part.
+	if offset > 0 && opts.checksum != nil {
+		if _, err := io.Copy(opts.checksum, io.NewSectionReader(file, 0, offset)); err != nil {
+			return fmt.Errorf("hashing partial file: %w", err)
+		}
+	}
+
 	var writer io.Writer = file
-	if opts.checksum != nil {
-		writer = io.MultiWriter(writer, opts.checksum)
+	if opts.disk != nil {
+		writer = io.MultiWriter(writer, opts.disk)
 	}
 
-	if opts.progress {
-		writer = &progressWriter{
-			w:         writer,
-			logger:    logger,
-			total:     contentLength,
-			startTime: time.Now(),
+	if err := stream(ctx, body, contentLength, offset, writer, logger, opts); err != nil {
+		if errors.Is(err, ErrChecksumMismatch) {
+			keepPartial = false
 		}
+		return err
 	}
 
-	n, err := io.Copy(writer, body)
-	if err != nil {
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("syncing temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+	if err := os.Rename(file.Name(), destPath); err != nil {
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+
+	successful = true
+
+	return nil
+}
+
+// HandleTo streams body to w rather than a file, decompressing it and
+// verifying its Content-Length and checksum as [Handle] does. Since w
+// can't be rolled back, it may have received some or all of the body
+// when an error is returned, including a checksum mismatch. Options that
+// need a file, [WithResume] and [WithSkipExisting], are rejected, and
+// [WithParallel] and [WithDiskBudget] have no effect.
+func HandleTo(ctx context.Context, body io.Reader, contentLength int64, w io.Writer, logger *slog.Logger, opts Options) error {
+	if opts.resume || opts.skipExisting {
+		return errors.New("WithResume and WithSkipExisting require a destination path")
+	}
+
+	return stream(ctx, body, contentLength, 0, w, logger, opts)
+}
+
+// stream copies body to w, decompressing it and feeding the checksum and
+// progress as set by opts, then checks the bytes received against
+// contentLength and verifies the checksum. offset is the number of bytes
+// of the file already written before body, for progress.
+func stream(ctx context.Context, body io.Reader, contentLength, offset int64, w io.Writer, logger *slog.Logger, opts Options) error {
+	if opts.maxSize > 0 {
+		if !opts.decompress && contentLength >= 0 && offset+contentLength > opts.maxSize {
+			return maxSizeError(opts.maxSize)
+		}
+
+		w = &limitWriter{w: w, n: offset, max: opts.maxSize}
+	}
+
+	received := &countingReader{r: &contextReader{ctx: ctx, r: body}}
+	body = received
+
+	if opts.checksum != nil && opts.checksumEncoded {
+		body = io.TeeReader(body, opts.checksum)
+	}
+
+	if opts.decompress {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("decompressing body: %w", err)
+		}
+		defer gz.Close()
+
+		body = gz
+	}
+
+	writer := w
+	if opts.checksum != nil && !opts.checksumEncoded {
+		writer = io.MultiWriter(writer, opts.checksum)
+	}
+
+	total := contentLength
+	switch {
+	case opts.decompress:
+		total = -1 // The decompressed size isn't known up front.
+	case total >= 0:
+		total += offset
+	}
+
+	progress := newProgressWriter(writer, logger, opts, offset, total)
+	if progress != nil {
+		writer = progress
+	}
+
+	if _, err := io.Copy(writer, body); err != nil {
 		if errors.Is(err, context.Canceled) {
 			return fmt.Errorf("%w: %w", ErrDownloadCancelled, err)
 		}
+		if errors.Is(err, ErrMaxSizeExceeded) {
+			return err
+		}
 
-		return fmt.Errorf("copying file body: %w", err)
+		return fmt.Errorf("copying body: %w", err)
 	}
+	progress.finish()
 
-	if contentLength >= 0 && n != contentLength {
+	if contentLength >= 0 && received.n != contentLength {
 		return &Error{
 			Err:    ErrContentLengthMismatch,
-			Detail: fmt.Sprintf("expected %d bytes, got %d", contentLength, n),
+			Detail: fmt.Sprintf("expected %d bytes, got %d", contentLength, received.n),
 		}
 	}
 
-	if err := opts.checksum.Verify(); err != nil {
-		return err
+	return opts.checksum.Verify()
+}
+
+// openTemp opens the file the body is written to before it's renamed to
+// destPath. With resume, that's the partial file for destPath, truncated
+// unless offset bytes of it are being resumed.
+func openTemp(destPath string, offset int64, resume bool) (*os.File, error) {
+	if !resume {
+		file, err := os.CreateTemp(filepath.Dir(destPath), ".httper-dl-*")
+		if err != nil {
+			return nil, fmt.Errorf("creating temp file: %w", err)
+		}
+		return file, nil
 	}
 
-	if err := file.Sync(); err != nil {
-		return fmt.Errorf("syncing temp file: %w", err)
+	if offset == 0 {
+		file, err := os.OpenFile(partialPath(destPath), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("creating partial file: %w", err)
+		}
+		return file, nil
 	}
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("closing temp file: %w", err)
+
+	file, err := os.OpenFile(partialPath(destPath), os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return nil, fmt.Errorf("opening partial file: %w", err)
 	}
-	if err := os.Rename(file.Name(), destPath); err != nil {
-		return fmt.Errorf("renaming temp file: %w", err)
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("stat partial file: %w", err)
+	}
+	if info.Size() != offset {
+		file.Close()
+		return nil, fmt.Errorf("partial file has %d bytes, resuming from %d", info.Size(), offset)
 	}
 
-	successful = true
+	return file, nil
+}
 
-	return nil
+// partialPath returns the path of the partial file kept by [WithResume].
+func partialPath(destPath string) string {
+	return filepath.Join(filepath.Dir(destPath), ".httper-dl-"+filepath.Base(destPath)+".part")
 }
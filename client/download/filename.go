@@ -0,0 +1,66 @@
+package download
+
+import (
+	"errors"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WithFilenameFromHeader lets destPath name a directory, with the file
+// named by the response's Content-Disposition filename, falling back to the
+// last segment of the URL path. Any directories in the name are dropped, so
+// it can't escape destPath. A destPath that isn't an existing directory is
+// used as is. It can't be combined with [WithResume] or [WithParallel].
+func WithFilenameFromHeader() Option {
+	return func(opts *Options) error {
+		opts.filenameFromHeader = true
+		return nil
+	}
+}
+
+// ResolvePath returns the file to download to under [WithFilenameFromHeader]
+// when destPath is a directory, named by the contentDisposition header or
+// else by u. Otherwise it returns destPath unchanged.
+func (o Options) ResolvePath(destPath, contentDisposition string, u *url.URL) (string, error) {
+	if !o.filenameFromHeader {
+		return destPath, nil
+	}
+
+	info, err := os.Stat(destPath)
+	if err != nil || !info.IsDir() {
+		return destPath, nil
+	}
+
+	name := ""
+	if _, params, err := mime.ParseMediaType(contentDisposition); err == nil {
+		name = sanitizeFilename(params["filename"])
+	}
+	if name == "" && u != nil {
+		name = sanitizeFilename(path.Base(u.Path))
+	}
+	if name == "" {
+		return "", errors.New("no filename in Content-Disposition or URL")
+	}
+
+	return filepath.Join(destPath, name), nil
+}
+
+// sanitizeFilename reduces name to its last path element, treating both
+// slash and backslash as separators, and rejects names that would refer
+// to a directory.
+func sanitizeFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		return ""
+	}
+
+	return name
+}
@@ -0,0 +1,125 @@
+package download
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DirEntry is a single file or subdirectory found in a directory listing.
+type DirEntry struct {
+	Name  string
+	URL   *url.URL
+	IsDir bool
+	Size  int64 // -1 when the listing does not report a size.
+}
+
+// ListingParser extracts the entries of a directory listing. base is
+// the URL the listing was fetched from, used to resolve relative links.
+type ListingParser func(base *url.URL, contentType string, body io.Reader) ([]DirEntry, error)
+
+// ParseAutoindex is the default [ListingParser]. It handles nginx's
+// JSON autoindex format when contentType is JSON, and otherwise scans
+// Apache/nginx autoindex HTML for links. Only direct children of base
+// are returned; parent directory and column-sort links are skipped.
+func ParseAutoindex(base *url.URL, contentType string, body io.Reader) ([]DirEntry, error) {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil && mt == "application/json" {
+		return parseAutoindexJSON(base, body)
+	}
+
+	return parseAutoindexHTML(base, body)
+}
+
+// hrefRe matches the href attribute of anchor tags in autoindex HTML.
+var hrefRe = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*["']([^"']+)["']`)
+
+func parseAutoindexHTML(base *url.URL, body io.Reader) ([]DirEntry, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("reading listing: %w", err)
+	}
+
+	var entries []DirEntry
+	seen := make(map[string]bool)
+	for _, m := range hrefRe.FindAllSubmatch(data, -1) {
+		entry, ok := childEntry(base, html.UnescapeString(string(m[1])))
+		if !ok || seen[entry.URL.String()] {
+			continue
+		}
+		seen[entry.URL.String()] = true
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func parseAutoindexJSON(base *url.URL, body io.Reader) ([]DirEntry, error) {
+	var items []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Size *int64 `json:"size"`
+	}
+	if err := json.NewDecoder(body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("decoding listing: %w", err)
+	}
+
+	entries := make([]DirEntry, 0, len(items))
+	for _, item := range items {
+		href := url.PathEscape(item.Name)
+		if item.Type == "directory" {
+			href += "/"
+		}
+
+		entry, ok := childEntry(base, href)
+		if !ok {
+			continue
+		}
+		if item.Size != nil {
+			entry.Size = *item.Size
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// childEntry resolves href against base, reporting false unless
+// the result is a direct child of base's directory.
+func childEntry(base *url.URL, href string) (DirEntry, bool) {
+	ref, err := url.Parse(href)
+	if err != nil || ref.RawQuery != "" && ref.Path == "" {
+		return DirEntry{}, false
+	}
+
+	dir := *base
+	dir.RawQuery, dir.Fragment = "", ""
+	if !strings.HasSuffix(dir.Path, "/") {
+		dir.Path += "/"
+		dir.RawPath = ""
+	}
+
+	resolved := dir.ResolveReference(ref)
+	resolved.RawQuery, resolved.Fragment = "", ""
+	if resolved.Scheme != dir.Scheme || resolved.Host != dir.Host {
+		return DirEntry{}, false
+	}
+
+	rest, ok := strings.CutPrefix(resolved.Path, dir.Path)
+	isDir := strings.HasSuffix(rest, "/")
+	rest = strings.TrimSuffix(rest, "/")
+	if !ok || rest == "" || strings.Contains(rest, "/") || rest == "." || rest == ".." {
+		return DirEntry{}, false
+	}
+
+	return DirEntry{
+		Name:  rest,
+		URL:   resolved,
+		IsDir: isDir,
+		Size:  -1,
+	}, true
+}
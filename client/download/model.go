@@ -12,6 +12,10 @@ var (
 	ErrContentLengthMismatch = errors.New("content length mismatch")
 	// ErrChecksumMismatch indicates the downloaded file's checksum did not match the expected value.
 	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrContentTypeMismatch indicates the response Content-Type did not match the expected prefix.
+	ErrContentTypeMismatch = errors.New("content type mismatch")
+	// ErrMaxSizeExceeded indicates the download was larger than the limit set by WithMaxSize.
+	ErrMaxSizeExceeded = errors.New("max size exceeded")
 	// ErrDownloadCancelled indicates the download was cancelled via context cancellation.
 	ErrDownloadCancelled = errors.New("download cancelled")
 )
@@ -46,6 +50,53 @@ func (r *contextReader) Read(p []byte) (int, error) {
 	}
 }
 
+// countingReader wraps an io.Reader, counting the bytes
+// received before any decompression.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+
+	return n, err
+}
+
+// limitWriter wraps an io.Writer, failing with [ErrMaxSizeExceeded]
+// rather than let more than max bytes be written in total, counting
+// from n.
+type limitWriter struct {
+	w   io.Writer
+	n   int64
+	max int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if lw.n+int64(len(p)) <= lw.max {
+		n, err := lw.w.Write(p)
+		lw.n += int64(n)
+		return n, err
+	}
+
+	n, err := lw.w.Write(p[:lw.max-lw.n])
+	lw.n += int64(n)
+	if err != nil {
+		return n, err
+	}
+
+	return n, maxSizeError(lw.max)
+}
+
+// maxSizeError reports a download larger than the max bytes allowed.
+func maxSizeError(limit int64) error {
+	return &Error{
+		Err:    ErrMaxSizeExceeded,
+		Detail: fmt.Sprintf("larger than %d bytes", limit),
+	}
+}
+
 // closedCh is a pre-closed channel reused for immediately-done Results,
 // avoiding a fresh make+close on every Add error path.
 var closedCh = func() chan struct{} {
@@ -1,8 +1,12 @@
 package download
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"hash"
+	"os"
+	"strings"
 )
 
 // Option is a functional option for configuring a download via [Handle].
@@ -10,10 +14,20 @@ type Option func(*Options) error
 
 // Options holds the resolved configuration for a single download.
 type Options struct {
-	checksum     *checksumVerifier
-	progress     bool
-	skipExisting bool
-	Group        *queue
+	checksum           *checksumVerifier
+	checksumEncoded    bool
+	decompress         bool
+	resume             bool
+	parallel           int
+	progress           bool
+	progressFn         func(downloaded, total int64)
+	skipExisting       bool
+	maxSize            int64
+	filenameFromHeader bool
+	diskBudget         int64
+	contentType        string
+	disk               *diskUsage
+	Group              *queue
 }
 
 // WithBatch activates batch mode by creating a queue with the given
@@ -24,6 +38,9 @@ func WithBatch(maxConcurrent int) Option {
 			return errors.New("WithBatch cannot be used with Result.Add")
 		}
 		opts.Group = newQueue(maxConcurrent)
+		if opts.diskBudget > 0 {
+			opts.Group.setDiskBudget(opts.diskBudget)
+		}
 		return nil
 	}
 }
@@ -35,9 +52,103 @@ func withBatch(queue *queue) Option {
 	}
 }
 
+// WithDiskBudget caps the combined size of in-progress temp files across
+// the queue. Before its request is sent, each download reserves its size,
+// as reported by a HEAD request, or the whole budget when that's unknown,
+// and waits while that doesn't fit in maxBytes alongside the downloads in
+// flight, resuming as they complete. See [Options.ReserveDisk]. It applies
+// to the whole batch and has no effect on synchronous downloads.
+func WithDiskBudget(maxBytes int64) Option {
+	return func(opts *Options) error {
+		if maxBytes <= 0 {
+			return errors.New("disk budget must be greater than zero")
+		}
+
+		opts.diskBudget = maxBytes
+		if opts.Group != nil {
+			opts.Group.setDiskBudget(maxBytes)
+		}
+		return nil
+	}
+}
+
+// DiskBudget reports whether the queue has a budget set by [WithDiskBudget].
+func (o Options) DiskBudget() bool {
+	if o.Group == nil {
+		return false
+	}
+
+	o.Group.mu.Lock()
+	defer o.Group.mu.Unlock()
+
+	return o.Group.diskBudget > 0
+}
+
+// ReserveDisk blocks until a download of size bytes fits in the queue's
+// disk budget, returning opts holding the reservation, which [HandleAt]
+// and [HandleParallel] count the temp file against, and a func releasing
+// it once they return. A negative size is unknown, as is any size under
+// [WithDecompress], and reserves the whole budget. Calling it before the
+// request is sent means a waiting download holds no connection. Without
+// a budget opts is returned unchanged.
+func (o Options) ReserveDisk(ctx context.Context, size int64) (Options, func(), error) {
+	if o.Group == nil {
+		return o, func() {}, nil
+	}
+
+	// Decompressed, the file's size isn't the Content-Length.
+	if o.decompress {
+		size = -1
+	}
+
+	usage, err := o.Group.reserveDisk(ctx, size)
+	if err != nil {
+		return o, func() {}, fmt.Errorf("waiting for disk budget: %w", err)
+	}
+	o.disk = usage
+
+	return o, usage.release, nil
+}
+
+// WithExpectContentType aborts the download before anything is written
+// unless the response Content-Type starts with prefix, compared
+// case-insensitively, e.g. "application/octet-stream" or "image/". It
+// catches servers that answer 200 OK with an HTML error page.
+func WithExpectContentType(prefix string) Option {
+	return func(opts *Options) error {
+		if prefix == "" {
+			return errors.New("expected content type must not be empty")
+		}
+
+		opts.contentType = strings.ToLower(prefix)
+		return nil
+	}
+}
+
+// CheckContentType reports an [ErrContentTypeMismatch] if contentType
+// doesn't match the prefix set by [WithExpectContentType], if any.
+func (o Options) CheckContentType(contentType string) error {
+	if o.contentType == "" {
+		return nil
+	}
+
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), o.contentType) {
+		return &Error{
+			Err:    ErrContentTypeMismatch,
+			Detail: fmt.Sprintf("expected %q, got %q", o.contentType, contentType),
+		}
+	}
+
+	return nil
+}
+
 // WithChecksum enables checksum validation of the downloaded file.
 // h is a [hash.Hash] instance (e.g. sha256.New()), and expected is the
-// hex-encoded expected checksum string.
+// hex-encoded expected checksum string. The checksum covers the bytes
+// written to disk, so it's of the decompressed content under
+// [WithDecompress], unless [WithChecksumOfEncoded] is set. It may be
+// given more than once, along with [WithChecksumString], and the download
+// passes if any of the checksums match.
 func WithChecksum(h hash.Hash, expected string) Option {
 	return func(opts *Options) error {
 		if h == nil {
@@ -48,7 +159,112 @@ func WithChecksum(h hash.Hash, expected string) Option {
 			return errors.New("expected checksum must not be empty")
 		}
 
-		opts.checksum = &checksumVerifier{hash: h, expected: expected}
+		opts.checksum = opts.checksum.add(checksum{hash: h, expected: expected})
+		return nil
+	}
+}
+
+// WithChecksumString is [WithChecksum] for a checksum given as a string
+// such as "sha256:9f86d0...", naming the algorithm, one of md5, sha1,
+// sha256, sha384 or sha512. The digest may be hex or base64 encoded. Without
+// a prefix, the algorithm is inferred from the digest's size. A mismatch
+// reports the algorithm of each checksum that failed.
+func WithChecksumString(s string) Option {
+	return func(opts *Options) error {
+		c, err := parseChecksum(s)
+		if err != nil {
+			return err
+		}
+
+		opts.checksum = opts.checksum.add(c)
+		return nil
+	}
+}
+
+// WithChecksumOfEncoded makes [WithChecksum] verify the bytes as received,
+// before [WithDecompress] decompresses them, e.g. to match a checksum
+// published for a ".gz" file. Without [WithDecompress] both are the same.
+func WithChecksumOfEncoded() Option {
+	return func(opts *Options) error {
+		opts.checksumEncoded = true
+		return nil
+	}
+}
+
+// WithDecompress gunzips the response body before it's written to disk,
+// e.g. to store "data.csv.gz" as "data.csv". The Content-Length check
+// applies to the compressed bytes received.
+func WithDecompress() Option {
+	return func(opts *Options) error {
+		opts.decompress = true
+		return nil
+	}
+}
+
+// WithResume keeps the bytes of a failed download in a partial file next to
+// destPath, named ".httper-dl-<name>.part", so a later download to the same
+// path requests only the rest with a Range header. If the server doesn't
+// answer with 206 Partial Content, the file is downloaded in full instead.
+// The checksum of [WithChecksum] covers the whole assembled file. Only one
+// download to a given destPath may run at a time, and it can't be combined
+// with [WithDecompress].
+func WithResume() Option {
+	return func(opts *Options) error {
+		opts.resume = true
+		return nil
+	}
+}
+
+// ResumeOffset returns the number of bytes already downloaded to the
+// partial file for destPath under [WithResume], or 0 if there's nothing
+// to resume.
+func (o Options) ResumeOffset(destPath string) int64 {
+	if !o.resume {
+		return 0
+	}
+
+	info, err := os.Stat(partialPath(destPath))
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
+
+// WithParallel downloads the file in the given number of byte ranges
+// concurrently, via [HandleParallel], when the server advertises
+// "Accept-Ranges: bytes" and a known Content-Length. Otherwise the file is
+// downloaded sequentially. It can't be combined with [WithResume] or
+// [WithDecompress].
+func WithParallel(chunks int) Option {
+	return func(opts *Options) error {
+		if chunks <= 0 {
+			return errors.New("chunks must be greater than zero")
+		}
+
+		opts.parallel = chunks
+		return nil
+	}
+}
+
+// Parallel returns the number of chunks set by [WithParallel], or 0 if unset.
+func (o Options) Parallel() int {
+	return o.parallel
+}
+
+// WithMaxSize aborts the download with [ErrMaxSizeExceeded] once more
+// than maxBytes would be written, removing the temp file. It's enforced
+// while streaming, so it also catches a body with no Content-Length, or
+// one larger than advertised. Under [WithDecompress] it limits the
+// decompressed size. A Content-Length known to be over the limit fails
+// before anything is written.
+func WithMaxSize(maxBytes int64) Option {
+	return func(opts *Options) error {
+		if maxBytes <= 0 {
+			return errors.New("max size must be greater than zero")
+		}
+
+		opts.maxSize = maxBytes
 		return nil
 	}
 }
@@ -62,6 +278,22 @@ func WithProgress() Option {
 	}
 }
 
+// WithProgressFunc calls fn with the bytes downloaded so far and the
+// expected total at most every 100ms while the body is streamed, and once
+// more when it's complete, e.g. to drive a progress bar. total is -1 when
+// the Content-Length is unknown. It's called from the downloading goroutine,
+// so fn should return quickly. It can be combined with [WithProgress].
+func WithProgressFunc(fn func(downloaded, total int64)) Option {
+	return func(opts *Options) error {
+		if fn == nil {
+			return errors.New("progress func must not be nil")
+		}
+
+		opts.progressFn = fn
+		return nil
+	}
+}
+
 // WithSkipExisting causes [Handle] to return nil immediately when
 // the destination file already exists, avoiding a redundant download.
 func WithSkipExisting() Option {
@@ -0,0 +1,170 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FetchRangeFunc requests the bytes from start to end inclusive of the
+// file being downloaded, passing the response body to fn.
+type FetchRangeFunc func(ctx context.Context, start, end int64, fn func(body io.Reader) error) error
+
+// HandleParallel downloads a file of size bytes to destPath in the number of
+// chunks set by [WithParallel], fetching each byte range concurrently with
+// fetch and writing it at its offset in a temp file in the same directory.
+// The temp file is renamed on success and removed on any error, which
+// cancels the remaining chunks. The checksum of [WithChecksum] is verified
+// over the assembled file. It can't be combined with [WithResume],
+// [WithDecompress] or [WithFilenameFromHeader].
+func HandleParallel(ctx context.Context, size int64, destPath string, logger *slog.Logger, opts Options, fetch FetchRangeFunc) error {
+	if opts.resume || opts.decompress || opts.filenameFromHeader {
+		return errors.New("WithParallel cannot be used with WithResume, WithDecompress or WithFilenameFromHeader")
+	}
+	if size <= 0 {
+		return fmt.Errorf("invalid size %d for parallel download", size)
+	}
+	if opts.maxSize > 0 && size > opts.maxSize {
+		return maxSizeError(opts.maxSize)
+	}
+
+	if opts.skipExisting {
+		if _, err := os.Stat(destPath); err == nil {
+			logger.Info("skipping existing file", "path", destPath)
+			return nil
+		}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(destPath), ".httper-dl-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+
+	var successful bool
+	defer func() {
+		if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			logger.Error("defer closing temp file", "error", err)
+		}
+		if !successful {
+			if err := os.Remove(file.Name()); err != nil {
+				logger.Error("failed to remove temp file", "error", err)
+			}
+		}
+	}()
+
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("sizing temp file: %w", err)
+	}
+
+	// Bytes from every chunk are counted through one writer, so the
+	// disk budget and progress see the download as a whole.
+	counted := io.Discard
+	if opts.disk != nil {
+		counted = opts.disk
+	}
+	progress := newProgressWriter(counted, logger, opts, 0, size)
+	if progress != nil {
+		counted = progress
+	}
+	counter := &syncWriter{w: counted}
+
+	if err := fetchChunks(ctx, file, size, opts.parallel, counter, fetch); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("%w: %w", ErrDownloadCancelled, err)
+		}
+
+		return err
+	}
+	progress.finish()
+
+	if opts.checksum != nil {
+		if _, err := io.Copy(opts.checksum, io.NewSectionReader(file, 0, size)); err != nil {
+			return fmt.Errorf("hashing temp file: %w", err)
+		}
+	}
+
+	if err := opts.checksum.Verify(); err != nil {
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("syncing temp file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+	if err := os.Rename(file.Name(), destPath); err != nil {
+		return fmt.Errorf("renaming temp file: %w", err)
+	}
+
+	successful = true
+
+	return nil
+}
+
+// fetchChunks splits size bytes into at most chunks byte ranges and fetches
+// them concurrently into file at their offsets, also writing each to
+// counter. It returns the first error, cancelling the other fetches.
+func fetchChunks(ctx context.Context, file *os.File, size int64, chunks int, counter io.Writer, fetch FetchRangeFunc) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	chunkSize := (size + int64(chunks) - 1) / int64(chunks)
+
+	var wg sync.WaitGroup
+	for start := int64(0); start < size; start += chunkSize {
+		end := min(start+chunkSize, size) - 1
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := fetch(ctx, start, end, func(body io.Reader) error {
+				dst := io.MultiWriter(io.NewOffsetWriter(file, start), counter)
+
+				n, err := io.Copy(dst, &contextReader{ctx: ctx, r: body})
+				if err != nil {
+					return fmt.Errorf("copying chunk body: %w", err)
+				}
+
+				if want := end - start + 1; n != want {
+					return &Error{
+						Err:    ErrContentLengthMismatch,
+						Detail: fmt.Sprintf("chunk at %d: expected %d bytes, got %d", start, want, n),
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				cancel(fmt.Errorf("chunk %d-%d: %w", start, end, err))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// syncWriter serializes writes to w from concurrent chunks.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	return sw.w.Write(p)
+}
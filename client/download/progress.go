@@ -7,33 +7,85 @@ import (
 	"time"
 )
 
-// progressWriter is an io.Writer, logging download progress at
-// most once per second if enabled.
+// progressFuncInterval is the minimum interval between calls to the
+// func set by [WithProgressFunc].
+const progressFuncInterval = 100 * time.Millisecond
+
+// progressWriter is an io.Writer, logging download progress at most
+// once per second if logger is set, and reporting it to fn at most
+// once per progressFuncInterval if set.
 type progressWriter struct {
 	w           io.Writer
 	logger      *slog.Logger
+	fn          func(downloaded, total int64)
 	transferred int64
 	total       int64
 	startTime   time.Time
 	lastLog     time.Time
+	lastCall    time.Time
+	reported    int64
+}
+
+// newProgressWriter returns a progressWriter over w for the progress
+// options set, or nil if neither [WithProgress] nor [WithProgressFunc] is.
+func newProgressWriter(w io.Writer, logger *slog.Logger, opts Options, transferred, total int64) *progressWriter {
+	if !opts.progress && opts.progressFn == nil {
+		return nil
+	}
+
+	pw := &progressWriter{
+		w:           w,
+		fn:          opts.progressFn,
+		transferred: transferred,
+		total:       total,
+		startTime:   time.Now(),
+		reported:    -1,
+	}
+	if opts.progress {
+		pw.logger = logger
+	}
+
+	return pw
 }
 
 func (pw *progressWriter) Write(p []byte) (int, error) {
 	n, err := pw.w.Write(p)
 	pw.transferred += int64(n)
 
-	if time.Since(pw.lastLog) >= time.Second {
-		pw.lastLog = time.Now()
-		pw.log("downloading")
+	if pw.logger != nil {
+		if time.Since(pw.lastLog) >= time.Second {
+			pw.lastLog = time.Now()
+			pw.log("downloading")
+		}
+
+		if pw.total >= 0 && pw.transferred == pw.total {
+			pw.log("download complete")
+		}
 	}
 
-	if pw.total >= 0 && pw.transferred == pw.total {
-		pw.log("download complete")
+	if pw.fn != nil && time.Since(pw.lastCall) >= progressFuncInterval {
+		pw.lastCall = time.Now()
+		pw.report()
 	}
 
 	return n, err
 }
 
+// finish reports the final progress to fn once the body is fully written,
+// unless the last call already did.
+func (pw *progressWriter) finish() {
+	if pw == nil || pw.fn == nil || pw.reported == pw.transferred {
+		return
+	}
+
+	pw.report()
+}
+
+func (pw *progressWriter) report() {
+	pw.reported = pw.transferred
+	pw.fn(pw.transferred, pw.total)
+}
+
 func (pw *progressWriter) log(msg string) {
 	elapsed := time.Since(pw.startTime)
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"sync"
+	"sync/atomic"
 )
 
 // WorkFunc is the signature for a unit of asynchronous work managed by a [queue].
@@ -22,13 +23,19 @@ type queue struct {
 	errs      []error
 	cancelAll chan struct{}
 	closeOnce sync.Once
+
+	diskBudget int64
+	diskActive map[*diskUsage]struct{}
+	diskFreed  chan struct{}
 }
 
 // newQueue creates a queue with the given concurrency limit.
 // If maxConcurrent <= 0, concurrency is unlimited.
 func newQueue(maxConcurrent int) *queue {
 	q := &queue{
-		cancelAll: make(chan struct{}),
+		cancelAll:  make(chan struct{}),
+		diskActive: make(map[*diskUsage]struct{}),
+		diskFreed:  make(chan struct{}),
 	}
 	if maxConcurrent > 0 {
 		q.sem = make(chan struct{}, maxConcurrent)
@@ -106,3 +113,91 @@ func (q *queue) recordErr(err error) {
 	defer q.mu.Unlock()
 	q.errs = append(q.errs, err)
 }
+
+// setDiskBudget sets the maximum number of bytes the queue's in-progress
+// temp files may occupy before new downloads are held back.
+func (q *queue) setDiskBudget(maxBytes int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.diskBudget = maxBytes
+	q.notifyDiskFreed()
+}
+
+// reserveDisk blocks until a temp file of size bytes fits in the disk
+// budget alongside those in progress, then reserves it, returning nil if
+// no budget is set. A negative size is unknown, and reserves the whole
+// budget. A download is always
+// admitted when none are active so a single file larger than the budget
+// cannot stall the queue. The reservation is held until released.
+func (q *queue) reserveDisk(ctx context.Context, size int64) (*diskUsage, error) {
+	for {
+		q.mu.Lock()
+		if q.diskBudget <= 0 {
+			q.mu.Unlock()
+			return nil, nil
+		}
+
+		if size < 0 {
+			size = q.diskBudget
+		}
+
+		if len(q.diskActive) == 0 || q.diskUsed()+size <= q.diskBudget {
+			d := &diskUsage{q: q, reserved: size}
+			q.diskActive[d] = struct{}{}
+			q.mu.Unlock()
+			return d, nil
+		}
+		freed := q.diskFreed
+		q.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// diskUsed returns the bytes held by in-progress downloads, each counting
+// its reservation, or what it has written if that's more. q.mu must be held.
+func (q *queue) diskUsed() int64 {
+	var used int64
+	for d := range q.diskActive {
+		used += max(d.reserved, d.written.Load())
+	}
+
+	return used
+}
+
+// notifyDiskFreed wakes every download waiting on the disk budget.
+// q.mu must be held.
+func (q *queue) notifyDiskFreed() {
+	close(q.diskFreed)
+	q.diskFreed = make(chan struct{})
+}
+
+// diskUsage is an io.Writer, counting the bytes written to a single
+// download's temp file against its disk budget reservation.
+type diskUsage struct {
+	q        *queue
+	reserved int64
+	written  atomic.Int64
+}
+
+func (d *diskUsage) Write(p []byte) (int, error) {
+	d.written.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// release returns the download's reservation to the budget once
+// its temp file has been renamed or removed.
+func (d *diskUsage) release() {
+	if d == nil {
+		return
+	}
+
+	d.q.mu.Lock()
+	defer d.q.mu.Unlock()
+	delete(d.q.diskActive, d)
+	d.q.notifyDiskFreed()
+}
@@ -275,3 +275,134 @@ func TestGroup_Wait_NilWhenAllSucceed(t *testing.T) {
 		t.Errorf("expected nil, got %v", err)
 	}
 }
+
+func TestGroup_DiskBudget(t *testing.T) {
+	const (
+		downloads = 8
+		size      = 4
+		budget    = 10 // Room for two at a time.
+	)
+
+	g := newQueue(0)
+	g.setDiskBudget(budget)
+
+	var inFlight, peak atomic.Int64
+	for range downloads {
+		g.Start(t.Context(), func(ctx context.Context) error {
+			usage, err := g.reserveDisk(ctx, size)
+			if err != nil {
+				return err
+			}
+			defer usage.release()
+
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+
+			for range size {
+				if _, err := usage.Write([]byte{0}); err != nil {
+					return err
+				}
+				time.Sleep(time.Millisecond)
+			}
+			return nil
+		}, nil)
+	}
+
+	if err := g.wait(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := peak.Load(); got > budget/size {
+		t.Errorf("peak in-flight downloads = %d, want at most %d", got, budget/size)
+	}
+}
+
+func TestGroup_DiskBudget_UnknownSize(t *testing.T) {
+	g := newQueue(0)
+	g.setDiskBudget(10)
+
+	first, err := g.reserveDisk(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := g.reserveDisk(ctx, -1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected an unknown size to wait for the whole budget, got %v", err)
+	}
+
+	first.release()
+	second, err := g.reserveDisk(t.Context(), -1)
+	if err != nil {
+		t.Fatalf("unexpected error after release: %v", err)
+	}
+	second.release()
+}
+
+func TestGroup_DiskBudget_WrittenOverReservation(t *testing.T) {
+	g := newQueue(0)
+	g.setDiskBudget(10)
+
+	first, err := g.reserveDisk(t.Context(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := first.Write(make([]byte, 9)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := g.reserveDisk(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to wait on bytes written past the reservation, got %v", err)
+	}
+
+	first.release()
+	second, err := g.reserveDisk(t.Context(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error after release: %v", err)
+	}
+	second.release()
+}
+
+func TestGroup_DiskBudget_OversizedSingle(t *testing.T) {
+	g := newQueue(0)
+	g.setDiskBudget(1)
+
+	usage, err := g.reserveDisk(t.Context(), 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := usage.Write(make([]byte, 100)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usage.release()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if used := g.diskUsed(); used != 0 {
+		t.Errorf("expected disk usage to be released, got %d", used)
+	}
+}
+
+func TestGroup_DiskBudget_ContextCancelled(t *testing.T) {
+	g := newQueue(0)
+	g.setDiskBudget(1)
+
+	usage, err := g.reserveDisk(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer usage.release()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := g.reserveDisk(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -1,11 +1,15 @@
 package client_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +17,9 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing/fstest"
 	"time"
 
 	"github.com/adamwoolhether/httper/client"
@@ -44,6 +51,15 @@ func ExampleURL() {
 	// Output: https://example.com:8443/api/v1?key=value
 }
 
+func ExampleJoinPath() {
+	base := client.URL("https", "api.example.com", "/v1/", client.WithQueryStrings(map[string]string{"key": "abc"}))
+
+	u := client.JoinPath(base, "files", "reports/2024 Q1.pdf")
+
+	fmt.Println(u.String())
+	// Output: https://api.example.com/v1/files/reports%2F2024%20Q1.pdf?key=abc
+}
+
 func ExampleRequest() {
 	type payload struct {
 		Name string `json:"name"`
@@ -85,6 +101,179 @@ func ExampleClient_Do() {
 	// Output: ok
 }
 
+func ExampleClient_DoMap() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":9007199254740993,"name":"alice"}`)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	m, err := c.DoMap(req, http.StatusOK)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(m["id"], m["name"])
+	// Output: 9007199254740993 alice
+}
+
+func ExampleClient_DoArray() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name":"alice"},{"name":"bob"}]`)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	err := c.DoArray(req, http.StatusOK, func(decode func(any) error) error {
+		var user struct {
+			Name string `json:"name"`
+		}
+		if err := decode(&user); err != nil {
+			return err
+		}
+
+		fmt.Println(user.Name)
+		return nil
+	})
+	if err != nil {
+		fmt.Println("error:", err)
+	}
+	// Output:
+	// alice
+	// bob
+}
+
+func ExampleClient_DoBatch() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":%q}`, r.URL.Path[1:])
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+
+	type item struct {
+		ID string `json:"id"`
+	}
+	items := make([]item, 3)
+
+	var reqs []client.BatchReq
+	for i, id := range []string{"a", "b", "c"} {
+		u, _ := url.Parse(ts.URL + "/" + id)
+		req, _ := client.Request(context.Background(), u, http.MethodGet)
+		reqs = append(reqs, client.BatchReq{Req: req, ExpCode: http.StatusOK, Dest: &items[i]})
+	}
+
+	for i, res := range c.DoBatch(context.Background(), reqs, client.WithConcurrency(2)) {
+		if res.Err != nil {
+			fmt.Println("error:", res.Err)
+			continue
+		}
+		fmt.Println(items[i].ID)
+	}
+	// Output:
+	// a
+	// b
+	// c
+}
+
+func ExampleClient_Fire() {
+	received := make(chan string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/ping")
+
+	// Typically the handler's context, which is cancelled once it returns.
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := client.Request(ctx, u, http.MethodPost)
+
+	c.Fire(req, http.StatusOK)
+	cancel()
+
+	fmt.Println(<-received)
+	// Output: /ping
+}
+
+func ExampleDoResult() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"detail":"user not found"}`)
+	}))
+	defer ts.Close()
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	type apiError struct {
+		Detail string `json:"detail"`
+	}
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	usr, apiErr, err := client.DoResult[user, apiError](c, req, http.StatusOK)
+	if apiErr != nil {
+		fmt.Println("api error:", apiErr.Detail)
+		return
+	}
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(usr.Name)
+	// Output: api error: user not found
+}
+
+func ExampleClient_LongPoll() {
+	var n atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusNoContent) // nothing new yet
+			return
+		}
+		fmt.Fprintf(w, "event %d", n.Load()/2)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	buildReq := func() (*http.Request, error) {
+		return client.Request(ctx, u, http.MethodGet)
+	}
+
+	var received int
+	onUpdate := func(b []byte) error {
+		fmt.Println(string(b))
+		if received++; received == 2 {
+			cancel()
+		}
+		return nil
+	}
+
+	err := c.LongPoll(ctx, buildReq, onUpdate, client.LongPollOptions{MinBackoff: 100 * time.Millisecond})
+	fmt.Println(err)
+	// Output:
+	// event 1
+	// event 2
+	// context canceled
+}
+
 func ExampleClient_Request() {
 	c, _ := client.Build()
 
@@ -218,6 +407,31 @@ func ExampleClient_DownloadAsync_batch() {
 	// file:/b
 }
 
+func ExampleClient_ListDirectory() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<a href="../">../</a><a href="a.bin">a.bin</a><a href="docs/">docs/</a>`)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/pub/")
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	entries, err := c.ListDirectory(req, http.StatusOK)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	for _, e := range entries {
+		fmt.Println(e.Name, e.IsDir, e.URL.Path)
+	}
+	// Output:
+	// a.bin false /pub/a.bin
+	// docs true /pub/docs/
+}
+
 // ————————————————————————————————————————————————————————————————————
 // Build option examples
 // ————————————————————————————————————————————————————————————————————
@@ -253,6 +467,36 @@ func ExampleWithTransport() {
 	// Output: ok
 }
 
+func ExampleFSTransport() {
+	fsys := fstest.MapFS{
+		"files/hello.txt": &fstest.MapFile{Data: []byte("hello from fs")},
+	}
+
+	c, err := client.Build(client.WithTransport(client.FSTransport(fsys)))
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://fs.test/files/hello.txt", nil)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	destPath := filepath.Join(os.TempDir(), "httper-fs-example.txt")
+	defer os.Remove(destPath)
+
+	if err := c.Download(req, http.StatusOK, destPath); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	data, _ := os.ReadFile(destPath)
+	fmt.Println(string(data))
+	// Output: hello from fs
+}
+
 func ExampleWithTimeout() {
 	c, err := client.Build(client.WithTimeout(5 * time.Second))
 	if err != nil {
@@ -314,6 +558,133 @@ func ExampleWithNoFollowRedirects() {
 	// Output: error: <nil>
 }
 
+func ExampleWithByteThrottle() {
+	c, err := client.Build(client.WithByteThrottle(1<<20, 256<<10)) // 1 MiB/s, 256 KiB burst
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	_ = c
+	fmt.Println("ok")
+	// Output: ok
+}
+
+func ExampleWithAutoDecompress() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		fmt.Fprint(zw, `{"status":"ok"}`)
+		zw.Close()
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build(client.WithAutoDecompress())
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet,
+		client.WithHeaders(map[string][]string{"Accept-Encoding": {"gzip"}}),
+	)
+
+	var resp struct{ Status string }
+	if err := c.Do(req, http.StatusOK, client.WithDestination(&resp)); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(resp.Status)
+	// Output: ok
+}
+
+func ExampleWithBaseURL() {
+	c, _ := client.Build(client.WithBaseURL("https://api.example.com/api"))
+
+	req, _ := c.Request(context.Background(),
+		c.URL("", "", "/v1/users", client.WithQueryStrings(map[string]string{"page": "2"})),
+		http.MethodGet,
+	)
+
+	fmt.Println(req.URL)
+	// Output: https://api.example.com/api/v1/users?page=2
+}
+
+func ExampleWithRetry() {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	backoff := func(attempt int) time.Duration {
+		return time.Duration(attempt) * 10 * time.Millisecond
+	}
+
+	c, _ := client.Build(client.WithRetry(3, backoff))
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	err := c.Do(req, http.StatusOK)
+	fmt.Println("error:", err, "attempts:", hits.Load())
+	// Output: error: <nil> attempts: 3
+}
+
+func ExampleWithInterceptor() {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	// Count every round trip, including retries, by sitting inside the retry layer.
+	var sent atomic.Int32
+	counter := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			sent.Add(1)
+			return next.RoundTrip(r)
+		})
+	}
+
+	c, _ := client.Build(
+		client.WithRetry(3, func(int) time.Duration { return 0 }),
+		client.WithInterceptor(client.PositionThrottle, counter),
+	)
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	err := c.Do(req, http.StatusOK)
+	fmt.Println("error:", err, "round trips:", sent.Load())
+	// Output: error: <nil> round trips: 2
+}
+
+func ExampleWithDeadlinePropagation() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build(client.WithDeadlinePropagation(100 * time.Millisecond))
+
+	// Typically the inbound handler's context, carrying a deadline from a timeout middleware.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(ctx, u, http.MethodGet)
+
+	// The outbound call must now complete within ~1.9s.
+	err := c.Do(req, http.StatusOK)
+	fmt.Println("error:", err)
+	// Output: error: <nil>
+}
+
 func ExampleWithLogger() {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
@@ -376,6 +747,125 @@ func ExampleWithJSONNumb() {
 	// Output: 9007199254740993
 }
 
+func ExampleWithFormat() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Mislabelled response: XML body served as text/plain.
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, `<user><name>alice</name></user>`)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	var user struct {
+		Name string `xml:"name"`
+	}
+	if err := c.Do(req, http.StatusOK, client.WithDestination(&user), client.WithFormat(client.FormatXML)); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(user.Name)
+	// Output: alice
+}
+
+func ExampleWithBeforeSend() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"signature":%q}`, r.Header.Get("X-Signature"))
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/orders")
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	sign := func(r *http.Request) error {
+		r.Header.Set("X-Signature", "signed:"+r.URL.Path)
+		return nil
+	}
+
+	var resp struct {
+		Signature string `json:"signature"`
+	}
+	if err := c.Do(req, http.StatusOK, client.WithBeforeSend(sign), client.WithDestination(&resp)); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(resp.Signature)
+	// Output: signed:/orders
+}
+
+func ExampleWithResponseMeta() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/orders/42")
+		w.Header().Set("X-RateLimit-Remaining", "99")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/orders")
+	req, _ := client.Request(context.Background(), u, http.MethodPost)
+
+	var meta client.ResponseMeta
+	if err := c.Do(req, http.StatusCreated, client.WithResponseMeta(&meta)); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(meta.StatusCode, meta.Header.Get("Location"), meta.Header.Get("X-RateLimit-Remaining"))
+	// Output: 201 /orders/42 99
+}
+
+func ExampleWithResponseCookies() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/login")
+	req, _ := client.Request(context.Background(), u, http.MethodPost)
+
+	var cookies []*http.Cookie
+	if err := c.Do(req, http.StatusNoContent, client.WithResponseCookies(&cookies)); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	for _, ck := range cookies {
+		fmt.Println(ck.Name, ck.Value)
+	}
+	// Output: session abc123
+}
+
+func ExampleWithVerifyContentLength() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"alice"}`)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/users/1")
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	var user struct {
+		Name string `json:"name"`
+	}
+	if err := c.Do(req, http.StatusOK, client.WithDestination(&user), client.WithVerifyContentLength()); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(user.Name)
+	// Output: alice
+}
+
 // ————————————————————————————————————————————————————————————————————
 // Request option examples
 // ————————————————————————————————————————————————————————————————————
@@ -404,6 +894,55 @@ func ExampleWithPayload() {
 	// Output: hello
 }
 
+func ExampleWithFormPayload() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		fmt.Fprint(w, `{"grant":"`+r.PostForm.Get("grant_type")+`"}`)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+
+	req, _ := client.Request(context.Background(), u, http.MethodPost,
+		client.WithFormPayload(url.Values{"grant_type": {"client_credentials"}}),
+	)
+
+	var resp struct{ Grant string }
+	c.Do(req, http.StatusOK, client.WithDestination(&resp))
+	fmt.Println(resp.Grant)
+	// Output: client_credentials
+}
+
+func ExampleWithMultipart() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, _ := r.FormFile("avatar")
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		fmt.Fprintf(w, `{"user":%q,"file":%q,"size":%d}`, r.FormValue("user"), header.Filename, len(data))
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+
+	req, _ := client.Request(context.Background(), u, http.MethodPost,
+		client.WithMultipart(
+			map[string]string{"user": "alice"},
+			[]client.FileUpload{{FieldName: "avatar", FileName: "me.png", Content: strings.NewReader("png-bytes")}},
+		),
+	)
+
+	var resp struct {
+		User string
+		File string
+		Size int
+	}
+	c.Do(req, http.StatusOK, client.WithDestination(&resp))
+	fmt.Println(resp.User, resp.File, resp.Size)
+	// Output: alice me.png 9
+}
+
 func ExampleWithContentType() {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprint(w, `{"ct":"`+r.Header.Get("Content-Type")+`"}`)
@@ -444,6 +983,36 @@ func ExampleWithHeaders() {
 	// Output: req-123
 }
 
+func ExampleWithAccept() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/xml" {
+			w.Header().Set("Content-Type", "application/xml")
+			fmt.Fprint(w, `<user><name>alice</name></user>`)
+			return
+		}
+		fmt.Fprint(w, `{"name":"alice"}`)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+	req, _ := client.Request(context.Background(), u, http.MethodGet,
+		client.WithAccept("application/xml"),
+	)
+
+	// The XML decoder is selected from the response Content-Type.
+	var user struct {
+		Name string `xml:"name"`
+	}
+	if err := c.Do(req, http.StatusOK, client.WithDestination(&user)); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(user.Name)
+	// Output: alice
+}
+
 func ExampleWithCookies() {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("session")
@@ -468,6 +1037,30 @@ func ExampleWithCookies() {
 	// Output: abc123
 }
 
+func ExampleWithUploadProgress() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL)
+
+	var sent, total int64
+	req, _ := client.Request(context.Background(), u, http.MethodPost,
+		client.WithPayload(map[string]string{"msg": "hello"}),
+		client.WithUploadProgress(func(s, t int64) { sent, total = s, t }),
+	)
+
+	if err := c.Do(req, http.StatusOK); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(sent == total)
+	// Output: true
+}
+
 // ————————————————————————————————————————————————————————————————————
 // URL option examples
 // ————————————————————————————————————————————————————————————————————
@@ -489,6 +1082,15 @@ func ExampleWithQueryStrings() {
 	// page: 1
 }
 
+func ExampleWithRawQuery() {
+	u := client.URL("https", "example.com", "/search",
+		client.WithRawQuery("filter=a,b&sort=-created"),
+	)
+
+	fmt.Println(u.String())
+	// Output: https://example.com/search?filter=a,b&sort=-created
+}
+
 func ExampleWithPort() {
 	u := client.URL("https", "example.com", "/api",
 		client.WithPort(9090),
@@ -534,6 +1136,45 @@ func ExampleWithChecksum() {
 	// Output: verified content
 }
 
+func ExampleWithDecompress() {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("a,b\n1,2\n"))
+	zw.Close()
+
+	// Checksums published alongside a ".gz" are of the compressed bytes.
+	sum := sha256.Sum256(gz.Bytes())
+	publishedHex := hex.EncodeToString(sum[:])
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gz.Bytes())
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/data.csv.gz")
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	dest := filepath.Join(os.TempDir(), "httper-example-data.csv")
+	defer os.Remove(dest)
+
+	err := c.Download(req, http.StatusOK, dest,
+		download.WithDecompress(),
+		download.WithChecksum(sha256.New(), publishedHex),
+		download.WithChecksumOfEncoded(),
+	)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	data, _ := os.ReadFile(dest)
+	fmt.Print(string(data))
+	// Output:
+	// a,b
+	// 1,2
+}
+
 func ExampleWithProgress() {
 	body := []byte("progress content")
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -562,6 +1203,26 @@ func ExampleWithProgress() {
 	// Output: progress content
 }
 
+func ExampleWithExpectContentType() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>error page</html>"))
+	}))
+	defer ts.Close()
+
+	c, _ := client.Build()
+	u, _ := url.Parse(ts.URL + "/photo.jpg")
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	dest := filepath.Join(os.TempDir(), "httper-example-photo.jpg")
+	defer os.Remove(dest)
+
+	err := c.Download(req, http.StatusOK, dest, download.WithExpectContentType("image/"))
+
+	fmt.Println(errors.Is(err, download.ErrContentTypeMismatch))
+	// Output: true
+}
+
 func ExampleWithSkipExisting() {
 	body := []byte("original")
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -627,3 +1288,42 @@ func ExampleWithBatch() {
 	fmt.Println(string(data))
 	// Output: batch:/file
 }
+
+func ExampleWithDiskBudget() {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := []byte("file:" + r.URL.Path)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	c, _ := client.Build()
+
+	dest := filepath.Join(os.TempDir(), "httper-example-budget.bin")
+	defer os.Remove(dest)
+
+	u, _ := url.Parse(ts.URL + "/a")
+	req, _ := client.Request(context.Background(), u, http.MethodGet)
+
+	// Hold back downloads whose size, probed with a HEAD request before
+	// each is sent, would take in-progress temp files past 64MB.
+	r, err := c.DownloadAsync(req, http.StatusOK, dest,
+		download.WithBatch(4),
+		download.WithDiskBudget(64<<20),
+	)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	if err := r.Wait(); err != nil {
+		fmt.Println("batch error:", err)
+		return
+	}
+
+	data, _ := os.ReadFile(dest)
+	fmt.Println(string(data))
+	// Output: file:/a
+}
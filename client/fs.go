@@ -0,0 +1,107 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FSTransport returns an [http.RoundTripper] serving requests from fsys
+// instead of the network, for use with [WithTransport] in tests. A GET or
+// HEAD for "/dir/file.txt" answers 200 OK with the contents of
+// "dir/file.txt", its Content-Length, and a Content-Type from the file
+// extension. The URL's host is ignored. Missing files and directories
+// answer 404 Not Found, and other methods 405 Method Not Allowed.
+func FSTransport(fsys fs.FS) http.RoundTripper {
+	return fsTransport{fsys: fsys}
+}
+
+// fsTransport is an http.RoundTripper, reading responses from an fs.FS.
+type fsTransport struct {
+	fsys fs.FS
+}
+
+func (ft fsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		_ = r.Body.Close()
+	}
+
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		resp := fsResponse(r, http.StatusMethodNotAllowed)
+		resp.Header.Set("Allow", "GET, HEAD")
+		return resp, nil
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		return fsResponse(r, http.StatusNotFound), nil
+	}
+
+	file, err := ft.fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		return fsResponse(r, http.StatusNotFound), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening %q: %w", name, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("stat %q: %w", name, err)
+	}
+	if info.IsDir() {
+		_ = file.Close()
+		return fsResponse(r, http.StatusNotFound), nil
+	}
+
+	resp := fsResponse(r, http.StatusOK)
+	resp.ContentLength = info.Size()
+	resp.Header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	resp.Header.Set("Content-Type", "application/octet-stream")
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		resp.Header.Set("Content-Type", ct)
+	}
+
+	if r.Method == http.MethodHead {
+		_ = file.Close()
+		return resp, nil
+	}
+	resp.Body = file
+
+	return resp, nil
+}
+
+// fsResponse returns an empty response to r with the given status code.
+// Error statuses carry their status text as a plain text body.
+func fsResponse(r *http.Request, code int) *http.Response {
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode: code,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    r,
+	}
+
+	if code >= http.StatusBadRequest && r.Method != http.MethodHead {
+		text := http.StatusText(code)
+		resp.Body = io.NopCloser(strings.NewReader(text))
+		resp.ContentLength = int64(len(text))
+		resp.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	return resp
+}
@@ -1,9 +1,33 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // maxErrBodySize caps the amount of response body read when
@@ -12,17 +36,190 @@ import (
 // wrong status.
 const maxErrBodySize = 4 << 10 // 4KB
 
+// fireTimeout bounds how long a request sent by [Client.Fire] may run.
+const fireTimeout = 30 * time.Second
+
 // execFn represents a func to operate on a response.
 type execFn func(response *http.Response) error
 
+// beforeSendFn represents a func to mutate a request just before it's sent.
+type beforeSendFn func(request *http.Request) error
+
+// execHooks are optional funcs run by exec around sending a request.
+// beforeSend is called just before the request is sent, and onResponse
+// as soon as a response arrives, before its status or body is checked.
+// acceptPartial also accepts 206 Partial Content in place of the expected
+// status, for requests sent with a Range header, and accept lists further
+// statuses taken as success. timings, if set, records the request's
+// connection phases.
+type execHooks struct {
+	beforeSend    beforeSendFn
+	onResponse    func(resp *http.Response)
+	acceptPartial bool
+	accept        []int
+	timings       *timingsRecorder
+	// errorBody, if set, is given the whole body of a response with an
+	// unexpected status, when it isn't empty.
+	errorBody func(body io.Reader) error
+}
+
 var (
 	// ErrUnexpectedStatusCode is the sentinel error wrapped by [UnexpectedStatusError].
 	ErrUnexpectedStatusCode = errors.New("unexpected status code")
 	// ErrAuthFailure is joined with [ErrUnexpectedStatusCode] when the server
 	// responds with 401 Unauthorized or 403 Forbidden.
 	ErrAuthFailure = errors.New("auth failure")
+	// ErrContentLengthMismatch indicates the response body length did not
+	// match its Content-Length header, see [WithVerifyContentLength].
+	ErrContentLengthMismatch = errors.New("content length mismatch")
+	// ErrMaxAttemptsExceeded indicates a call needed more round trips, across
+	// redirects and retries, than allowed by [WithMaxTotalAttempts].
+	ErrMaxAttemptsExceeded = errors.New("max total attempts exceeded")
+	// ErrClientClosed is returned when background work is started on a
+	// client after [Client.Close].
+	ErrClientClosed = errors.New("client closed")
+	// ErrDNSResolution indicates the request's host couldn't be resolved.
+	// It wraps the [*net.DNSError], whose IsNotFound tells a host that
+	// doesn't exist, likely a misconfigured URL, from a transient lookup
+	// failure such as a timeout.
+	ErrDNSResolution = errors.New("dns resolution failed")
+	// ErrPinMismatch fails a TLS handshake whose leaf certificate's public
+	// key isn't one pinned with [WithPinnedSPKI].
+	ErrPinMismatch = errors.New("certificate public key not pinned")
 )
 
+// attemptsKey is the context key of the [attemptBudget] for a call.
+type attemptsKey struct{}
+
+// attemptBudget counts the round trips made for a single call, shared by
+// its redirects and retries.
+type attemptBudget struct {
+	max  int64
+	used atomic.Int64
+}
+
+// take reports whether another round trip is within the budget.
+func (b *attemptBudget) take() bool {
+	return b.used.Add(1) <= b.max
+}
+
+// BatchReq is a single request executed by [Client.DoBatch].
+type BatchReq struct {
+	Req     *http.Request
+	ExpCode int
+	Dest    any // Optional pointer to decode the response body into.
+	Opts    []DoOption
+}
+
+// BatchResult is the outcome of the [BatchReq] at the same index.
+type BatchResult struct {
+	Req *http.Request
+	Err error
+}
+
+// ResponseMeta is the response metadata captured by [WithResponseMeta].
+type ResponseMeta struct {
+	StatusCode    int
+	Header        http.Header
+	ContentLength int64 // -1 if unknown.
+	Timings       Timings
+	Captured      http.Header // Set by WithCaptureResponseHeaders, nil if none matched.
+}
+
+// Timings are the durations of a request's connection phases, recorded
+// with [httptrace]. Phases that didn't happen are zero, such as DNS,
+// Connect and TLS on a reused connection. With [WithRetry], they're of
+// the last attempt.
+type Timings struct {
+	DNS        time.Duration // Resolving the host.
+	Connect    time.Duration // Dialing the TCP connection.
+	TLS        time.Duration // The TLS handshake.
+	FirstByte  time.Duration // From getting a connection to the first response byte.
+	Total      time.Duration // From sending the request to the response headers.
+	ConnReused bool          // Whether an idle connection was reused.
+}
+
+// timingsRecorder records [Timings] from an [httptrace.ClientTrace]. Its
+// hooks may run on the transport's dialing goroutines, hence the mutex.
+type timingsRecorder struct {
+	mu                                   sync.Mutex
+	start, dnsStart, connStart, tlsStart time.Time
+	gotConn                              time.Time
+	timings                              Timings
+}
+
+// trace returns a ClientTrace recording into tr, starting the clock.
+func (tr *timingsRecorder) trace() *httptrace.ClientTrace {
+	tr.mu.Lock()
+	tr.start = time.Now()
+	tr.mu.Unlock()
+
+	record := func(fn func(now time.Time)) {
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+		fn(time.Now())
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { record(func(now time.Time) { tr.dnsStart = now }) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func(now time.Time) { tr.timings.DNS = now.Sub(tr.dnsStart) })
+		},
+		// GetConn starts each attempt, so a retry records only its own phases.
+		GetConn: func(string) {
+			record(func(now time.Time) {
+				tr.start = now
+				tr.dnsStart, tr.connStart, tr.tlsStart, tr.gotConn = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+				tr.timings = Timings{}
+			})
+		},
+		// Dialing several addresses, the first dial starts the phase.
+		ConnectStart: func(_, _ string) {
+			record(func(now time.Time) {
+				if tr.connStart.IsZero() {
+					tr.connStart = now
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			record(func(now time.Time) {
+				if err == nil {
+					tr.timings.Connect = now.Sub(tr.connStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() { record(func(now time.Time) { tr.tlsStart = now }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func(now time.Time) { tr.timings.TLS = now.Sub(tr.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			record(func(now time.Time) {
+				tr.gotConn = now
+				tr.timings.ConnReused = info.Reused
+			})
+		},
+		GotFirstResponseByte: func() {
+			record(func(now time.Time) { tr.timings.FirstByte = now.Sub(tr.gotConn) })
+		},
+	}
+}
+
+// result returns the recorded timings, with Total up to now. A nil
+// recorder returns zero Timings.
+func (tr *timingsRecorder) result() Timings {
+	if tr == nil {
+		return Timings{}
+	}
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	t := tr.timings
+	t.Total = time.Since(tr.start)
+
+	return t
+}
+
 // UnexpectedStatusError is returned when the HTTP response status code
 // does not match the expected value.
 type UnexpectedStatusError struct {
@@ -38,3 +235,478 @@ func (e *UnexpectedStatusError) Error() string {
 func (e *UnexpectedStatusError) Unwrap() error {
 	return e.Err
 }
+
+// FileUpload is a file part of a multipart body built by [WithMultipart].
+type FileUpload struct {
+	FieldName string
+	FileName  string
+	Content   io.Reader
+}
+
+// multipartBody holds the parts written by [WithMultipart].
+type multipartBody struct {
+	fields map[string]string
+	files  []FileUpload
+}
+
+// write writes the fields, in key order, then the files to mw and closes it.
+func (m *multipartBody) write(mw *multipart.Writer) error {
+	for _, k := range slices.Sorted(maps.Keys(m.fields)) {
+		if err := mw.WriteField(k, m.fields[k]); err != nil {
+			return fmt.Errorf("writing field[%s]: %w", k, err)
+		}
+	}
+
+	for _, f := range m.files {
+		part, err := mw.CreateFormFile(f.FieldName, f.FileName)
+		if err != nil {
+			return fmt.Errorf("creating file[%s]: %w", f.FieldName, err)
+		}
+		if _, err := io.Copy(part, f.Content); err != nil {
+			return fmt.Errorf("writing file[%s]: %w", f.FieldName, err)
+		}
+	}
+
+	return mw.Close()
+}
+
+// fileUpload is a file opened for [WithFileUpload].
+type fileUpload struct {
+	path        string
+	file        *os.File
+	size        int64
+	contentType string
+}
+
+// openFileUpload opens the regular file at path, detecting its content
+// type from the extension, or else by sniffing its first 512 bytes.
+func openFileUpload(path string) (*fileUpload, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening upload file: %w", err)
+	}
+
+	upload, err := inspectFileUpload(path, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// inspectFileUpload builds the fileUpload for the opened file.
+func inspectFileUpload(path string, file *os.File) (*fileUpload, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat upload file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("upload file %s is not a regular file", path)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		sniff := make([]byte, 512)
+		n, err := io.ReadFull(file, sniff)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("reading upload file: %w", err)
+		}
+		contentType = http.DetectContentType(sniff[:n])
+
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("rewinding upload file: %w", err)
+		}
+	}
+
+	upload := fileUpload{
+		path:        path,
+		file:        file,
+		size:        info.Size(),
+		contentType: contentType,
+	}
+
+	return &upload, nil
+}
+
+// reopen opens the file again for [http.Request.GetBody].
+func (u *fileUpload) reopen() (io.ReadCloser, error) {
+	file, err := os.Open(u.path)
+	if err != nil {
+		return nil, fmt.Errorf("reopening upload file: %w", err)
+	}
+
+	return file, nil
+}
+
+// progressReader is an io.ReadCloser, reporting the number of
+// request body bytes read by the transport to fn.
+type progressReader struct {
+	rc    io.ReadCloser
+	fn    func(sent, total int64)
+	sent  int64
+	total int64
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.rc.Read(p)
+	if n > 0 {
+		pr.sent += int64(n)
+		pr.fn(pr.sent, pr.total)
+	}
+
+	return n, err
+}
+
+func (pr *progressReader) Close() error {
+	return pr.rc.Close()
+}
+
+// withUploadProgress wraps the request body, and any body produced by
+// GetBody, in a progressReader.
+func withUploadProgress(req *http.Request, fn func(sent, total int64)) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	total := int64(-1)
+	if req.ContentLength > 0 {
+		total = req.ContentLength
+	}
+
+	req.Body = &progressReader{rc: req.Body, fn: fn, total: total}
+
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+
+			return &progressReader{rc: body, fn: fn, total: total}, nil
+		}
+	}
+}
+
+// resolveURL joins the path of a relative ref onto base, taking the
+// query and fragment from ref, or the query from base if ref has none.
+// The escaped path is joined, as JoinPath expects, so an escaped "%" or
+// "/" in ref stays escaped. An absolute ref is returned unchanged.
+func resolveURL(base, ref *url.URL) *url.URL {
+	if ref.Scheme != "" || ref.Host != "" {
+		return ref
+	}
+
+	resolved := base.JoinPath(ref.EscapedPath())
+	if ref.RawQuery != "" {
+		resolved.RawQuery = ref.RawQuery
+	}
+	resolved.Fragment = ref.Fragment
+
+	return resolved
+}
+
+// decompressBody wraps resp.Body in a decoder for its gzip or deflate
+// Content-Encoding, leaving other responses untouched.
+func decompressBody(resp *http.Response) {
+	if resp.Uncompressed || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate":
+	default:
+		return
+	}
+
+	resp.Body = &decompressReader{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decompressReader is an io.ReadCloser, decoding the body on read. The
+// decoder is created lazily so empty bodies, e.g. for a 204, read as EOF.
+type decompressReader struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		d.r, d.err = newDecompressor(d.body, d.encoding)
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	return d.r.Read(p)
+}
+
+func (d *decompressReader) Close() error {
+	return d.body.Close()
+}
+
+// newDecompressor returns a decoder for encoding. The HTTP "deflate"
+// encoding is zlib-wrapped, but some servers send raw deflate, so the
+// zlib header is sniffed before choosing.
+func newDecompressor(r io.Reader, encoding string) (io.Reader, error) {
+	if encoding != "deflate" {
+		return gzip.NewReader(r)
+	}
+
+	br := bufio.NewReader(r)
+	if hdr, err := br.Peek(2); err == nil && hdr[0]&0x0f == 8 && (uint16(hdr[0])<<8|uint16(hdr[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
+
+// countingReader is an io.ReadCloser, counting the bytes read through it.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += int64(n)
+
+	return n, err
+}
+
+// countingBody is an io.ReadCloser, adding the bytes read through it to n,
+// which is safe to load while the transport is still sending the body.
+type countingBody struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (cb *countingBody) Read(p []byte) (int, error) {
+	n, err := cb.ReadCloser.Read(p)
+	cb.n.Add(int64(n))
+
+	return n, err
+}
+
+// countRequestBody returns a shallow copy of req whose body, and any body
+// replayed via GetBody, adds the bytes sent to n.
+func countRequestBody(req *http.Request, n *atomic.Int64) *http.Request {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req
+	}
+
+	counted := req.WithContext(req.Context())
+	counted.Body = &countingBody{ReadCloser: req.Body, n: n}
+
+	if getBody := req.GetBody; getBody != nil {
+		counted.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil || body == http.NoBody {
+				return body, err
+			}
+
+			return &countingBody{ReadCloser: body, n: n}, nil
+		}
+	}
+
+	return counted
+}
+
+// verifyContentLength reads whatever remains of the body and
+// checks the total bytes read against the declared length.
+func verifyContentLength(cr *countingReader, want int64) error {
+	if _, err := io.Copy(io.Discard, cr); err != nil {
+		return fmt.Errorf("%w: read %d of %d bytes: %w", ErrContentLengthMismatch, cr.n, want, err)
+	}
+
+	if cr.n != want {
+		return fmt.Errorf("%w: read %d of %d bytes", ErrContentLengthMismatch, cr.n, want)
+	}
+
+	return nil
+}
+
+// decodeBody decodes the response body into the configured destination,
+// choosing the decoder from the format setting or the response Content-Type.
+func decodeBody(resp *http.Response, settings doOpts) error {
+	format := settings.format
+	if format == FormatAuto {
+		format = FormatJSON
+		if isXML(resp.Header.Get("Content-Type")) {
+			format = FormatXML
+		}
+	}
+
+	if format == FormatXML {
+		return xml.NewDecoder(resp.Body).Decode(settings.responseBody)
+	}
+
+	d := json.NewDecoder(resp.Body)
+	if settings.useJSONNum {
+		d.UseNumber()
+	}
+
+	return d.Decode(settings.responseBody)
+}
+
+// emptyBody reports whether resp is known to have no body to decode.
+func emptyBody(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusNotModified:
+		return true
+	}
+
+	return resp.ContentLength == 0
+}
+
+func isXML(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mt == "application/xml" || mt == "text/xml" || strings.HasSuffix(mt, "+xml")
+}
+
+// isNoContent reports whether err is an [UnexpectedStatusError] for a 204 response.
+func isNoContent(err error) bool {
+	statusErr, ok := errors.AsType[*UnexpectedStatusError](err)
+	return ok && statusErr.StatusCode == http.StatusNoContent
+}
+
+// withCancelFrom derives a context from ctx, keeping its values and
+// deadline, that's also cancelled when other is, so a request bound to
+// both stops with whichever ends first. The returned cancel must be called
+// once the request is done.
+func withCancelFrom(ctx, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(other, func() { cancel(other.Err()) })
+
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+// isTimeout reports whether err is a request timeout rather than
+// a cancellation of the parent ctx.
+func isTimeout(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if netErr, ok := errors.AsType[net.Error](err); ok && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// spool holds a request body read ahead for [WithSpooledRetryBodies], in
+// memory or, past the memory limit, in a temp file. Each body it opens
+// holds a reference, as does the retry loop, and the temp file is removed
+// once the last is released, since the transport may close the body of
+// the final attempt after RoundTrip returns.
+type spool struct {
+	mem  []byte
+	file *os.File
+	size int64
+	refs atomic.Int64
+}
+
+// newSpool reads body to its end and closes it, keeping up to maxMem
+// bytes in memory and spilling the whole body to a temp file beyond that.
+// The returned spool holds the caller's reference.
+func newSpool(body io.ReadCloser, maxMem int64) (*spool, error) {
+	defer body.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(body, maxMem+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+
+	s := spool{mem: buf, size: int64(len(buf))}
+	s.refs.Store(1)
+	if s.size <= maxMem {
+		return &s, nil
+	}
+
+	file, err := os.CreateTemp("", "httper-spool-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating spool file: %w", err)
+	}
+	s.file, s.mem = file, nil
+
+	n, err := io.Copy(file, io.MultiReader(bytes.NewReader(buf), body))
+	if err != nil {
+		s.remove()
+		return nil, fmt.Errorf("spooling body: %w", err)
+	}
+	s.size = n
+
+	return &s, nil
+}
+
+// open returns a reader over the whole body, for [http.Request.GetBody].
+func (s *spool) open() (io.ReadCloser, error) {
+	s.refs.Add(1)
+
+	if s.file == nil {
+		return &spoolBody{Reader: bytes.NewReader(s.mem), s: s}, nil
+	}
+
+	return &spoolBody{Reader: io.NewSectionReader(s.file, 0, s.size), s: s}, nil
+}
+
+// release drops a reference, removing the temp file with the last one.
+func (s *spool) release() {
+	if s.refs.Add(-1) == 0 {
+		s.remove()
+	}
+}
+
+func (s *spool) remove() {
+	if s.file == nil {
+		return
+	}
+
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+}
+
+// spoolBody is an io.ReadCloser over a spool,
+// releasing its reference once closed.
+type spoolBody struct {
+	io.Reader
+	s    *spool
+	once sync.Once
+}
+
+func (sb *spoolBody) Close() error {
+	sb.once.Do(sb.s.release)
+	return nil
+}
+
+// verifyPins returns a [tls.Config.VerifyConnection] func, failing with
+// [ErrPinMismatch] unless the SHA-256 of the leaf certificate's
+// SubjectPublicKeyInfo is one of pins.
+func verifyPins(pins [][sha256.Size]byte) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("%w: no peer certificate", ErrPinMismatch)
+		}
+
+		leaf := cs.PeerCertificates[0]
+		if !slices.Contains(pins, sha256.Sum256(leaf.RawSubjectPublicKeyInfo)) {
+			return fmt.Errorf("%w: %s", ErrPinMismatch, leaf.Subject)
+		}
+
+		return nil
+	}
+}
@@ -1,12 +1,23 @@
 package client
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/adamwoolhether/httper/client/download"
 	"github.com/adamwoolhether/httper/client/throttle"
 )
 
@@ -18,8 +29,22 @@ type options struct {
 	timeout           *time.Duration
 	userAgent         string
 	throttle          *throttle.Config
+	byteThrottle      *throttle.ByteConfig
 	noFollowRedirects bool
 	logger            *slog.Logger
+	deadlineBuffer    *time.Duration
+	autoDecompress    bool
+	baseURL           *url.URL
+	retry             *retry
+	retryStatuses     []int
+	spoolMaxMem       *int64
+	maxTotalAttempts  int
+	interceptors      map[Position][]func(http.RoundTripper) http.RoundTripper
+	clientTrace       func(*http.Request) *httptrace.ClientTrace
+	byteMetrics       ByteMetricsFunc
+	pins              [][sha256.Size]byte
+	captureHeaders    []string
+	minTLSVersion     uint16
 }
 
 // WithClient replaces the default [http.Client] used by the [Client].
@@ -74,6 +99,111 @@ func WithThrottle(rps, burst int) Option {
 	}
 }
 
+// WithByteThrottle limits the combined read throughput of all response
+// bodies to bytesPerSec, with bursts of up to burst bytes, bounding the
+// client's total download bandwidth. It complements [WithThrottle], which
+// limits requests rather than bytes.
+func WithByteThrottle(bytesPerSec, burst int64) Option {
+	return func(c *options) error {
+		if bytesPerSec <= 0 || burst <= 0 {
+			return fmt.Errorf("bytes per sec[%d] and burst[%d] %w", bytesPerSec, burst, throttle.ErrMustNotBeZero)
+		}
+		c.byteThrottle = &throttle.ByteConfig{BytesPerSec: bytesPerSec, Burst: burst}
+		return nil
+	}
+}
+
+// WithCaptureResponseHeaders names the response headers copied into
+// [ResponseMeta].Captured by [WithResponseMeta], such as an upstream's
+// X-Cache, so audit logs record just those instead of the whole header set.
+// Names are case-insensitive; headers a response doesn't set are left out.
+func WithCaptureResponseHeaders(names ...string) Option {
+	return func(c *options) error {
+		if len(names) == 0 {
+			return errors.New("captured header names must not be empty")
+		}
+
+		canonical := make([]string, 0, len(names))
+		for _, name := range names {
+			if name == "" {
+				return errors.New("captured header name must not be empty")
+			}
+			canonical = append(canonical, http.CanonicalHeaderKey(name))
+		}
+
+		c.captureHeaders = canonical
+		return nil
+	}
+}
+
+// WithPinnedSPKI pins the server's public key: the TLS handshake fails with
+// [ErrPinMismatch] unless the SHA-256 of the leaf certificate's
+// SubjectPublicKeyInfo matches one of hashes, each base64 encoded, as in
+// HPKP's pin-sha256, optionally prefixed with "sha256/". The certificate
+// chain is still verified against the trusted CAs. Pin a backup key too,
+// so the server can rotate keys without breaking the client. The base
+// transport must be an [*http.Transport], whose TLS config is cloned.
+func WithPinnedSPKI(hashes ...string) Option {
+	return func(c *options) error {
+		if len(hashes) == 0 {
+			return errors.New("pinned hashes must not be empty")
+		}
+
+		pins := make([][sha256.Size]byte, 0, len(hashes))
+		for _, h := range hashes {
+			raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(h, "sha256/"))
+			if err != nil || len(raw) != sha256.Size {
+				return fmt.Errorf("invalid SPKI hash %q: want base64 of %d bytes", h, sha256.Size)
+			}
+			pins = append(pins, [sha256.Size]byte(raw))
+		}
+
+		c.pins = pins
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets the lowest TLS version the client negotiates, such
+// as [tls.VersionTLS13]. Default is that of [crypto/tls], TLS 1.2. The base
+// transport must be an [*http.Transport], whose TLS config is cloned.
+func WithMinTLSVersion(version uint16) Option {
+	return func(c *options) error {
+		if version < tls.VersionTLS12 || version > tls.VersionTLS13 {
+			return fmt.Errorf("unsupported min TLS version[%#04x]", version)
+		}
+		c.minTLSVersion = version
+		return nil
+	}
+}
+
+// tlsTransport returns base with the TLS settings of [WithPinnedSPKI] and
+// [WithMinTLSVersion] applied to a clone, or base as is without them.
+func (o *options) tlsTransport(base http.RoundTripper) (http.RoundTripper, error) {
+	if o.pins == nil && o.minTLSVersion == 0 {
+		return base, nil
+	}
+
+	t, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("TLS options need an *http.Transport base transport, got %T", base)
+	}
+
+	t = t.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	if o.minTLSVersion != 0 {
+		t.TLSClientConfig.MinVersion = o.minTLSVersion
+	}
+	if o.pins != nil {
+		// VerifyConnection, unlike VerifyPeerCertificate,
+		// also runs for resumed sessions.
+		t.TLSClientConfig.VerifyConnection = verifyPins(o.pins)
+	}
+
+	return t, nil
+}
+
 // WithNoFollowRedirects prevents the [Client] from following HTTP redirects.
 func WithNoFollowRedirects() Option {
 	return func(c *options) error {
@@ -90,6 +220,235 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
+// WithDeadlinePropagation shortens each outbound call's deadline to the
+// request context's deadline minus buffer, when the context has one.
+// Passing a handler's context to [Request] then leaves buffer of the
+// inbound budget to handle the downstream result, instead of the call
+// running until the inbound request itself times out.
+func WithDeadlinePropagation(buffer time.Duration) Option {
+	return func(c *options) error {
+		if buffer < 0 {
+			return errors.New("deadline buffer must not be negative")
+		}
+		c.deadlineBuffer = &buffer
+		return nil
+	}
+}
+
+// WithBaseURL sets the URL that relative request URLs passed to
+// [Client.Request] are joined onto. The base path is kept, so a base of
+// "https://api.example.com/api" and a path of "/v1/users" or "v1/users"
+// both give "https://api.example.com/api/v1/users". A request URL with a
+// scheme or host is used as is.
+func WithBaseURL(rawURL string) Option {
+	return func(c *options) error {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("parsing base url: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("base url[%s] must be absolute", rawURL)
+		}
+		c.baseURL = u
+		return nil
+	}
+}
+
+// WithAutoDecompress decodes response bodies sent with a gzip or deflate
+// Content-Encoding before they're read, removing the Content-Encoding and
+// Content-Length headers as the transport does when it decompresses
+// transparently. It's needed when requests set their own Accept-Encoding
+// header, which disables the transport's decompression. Identity and
+// unknown encodings are left untouched.
+func WithAutoDecompress() Option {
+	return func(c *options) error {
+		c.autoDecompress = true
+		return nil
+	}
+}
+
+// WithClientTrace attaches the [httptrace.ClientTrace] returned by fn to
+// each request's context before it's sent, for connection-level hooks such
+// as DNS, dial and TLS events. fn may return nil to skip a request. For
+// just the phase durations, use [ResponseMeta.Timings] via [WithResponseMeta].
+func WithClientTrace(fn func(*http.Request) *httptrace.ClientTrace) Option {
+	return func(c *options) error {
+		if fn == nil {
+			return errors.New("client trace func must not be nil")
+		}
+
+		c.clientTrace = fn
+		return nil
+	}
+}
+
+// ByteMetricsFunc receives the body bytes transferred by a call, see
+// [WithByteMetrics].
+type ByteMetricsFunc func(r *http.Request, sent, received int64)
+
+// WithByteMetrics calls fn once per call, such as [Client.Do] or
+// [Client.Download], with the request body bytes sent and the response
+// body bytes received, e.g. for bandwidth accounting per endpoint. sent
+// includes bodies replayed by redirects and [WithRetry]. received counts
+// the body as read from the transport, before [WithAutoDecompress], and
+// includes bytes discarded unread. fn is called after the response body
+// is closed, or once the request fails, from the calling goroutine.
+func WithByteMetrics(fn ByteMetricsFunc) Option {
+	return func(c *options) error {
+		if fn == nil {
+			return errors.New("byte metrics func must not be nil")
+		}
+
+		c.byteMetrics = fn
+		return nil
+	}
+}
+
+// WithRetry retries idempotent requests (GET, HEAD, OPTIONS, PUT, DELETE,
+// TRACE) on transient errors and retryable status codes, up to
+// maxAttempts round trips in total. Transient errors are connection resets,
+// refusals and EOFs, timeouts, and other network errors reported as
+// temporary; others, such as TLS handshake and certificate failures, a
+// [WithPinnedSPKI] mismatch or a host that doesn't exist (see
+// [ErrDNSResolution]), fail on the first attempt. backoff returns the wait before the
+// next attempt, given the 1-based attempt that just failed; the wait ends
+// early if the request context is cancelled. Requests with a body are only
+// retried when it can be rewound via GetBody, which [Request] sets for
+// [WithPayload]. The response of the last attempt is returned as is, so an
+// [UnexpectedStatusError] reflects it. Retries wrap [WithThrottle], so
+// every attempt is rate-limited.
+func WithRetry(maxAttempts int, backoff func(attempt int) time.Duration) Option {
+	return func(c *options) error {
+		if maxAttempts < 1 {
+			return errors.New("max attempts must be at least 1")
+		}
+		if backoff == nil {
+			return errors.New("backoff func must not be nil")
+		}
+		c.retry = &retry{maxAttempts: maxAttempts, backoff: backoff}
+		return nil
+	}
+}
+
+// WithRetryOnConnectionError retries idempotent requests up to maxRetries
+// times when the round trip fails with a connection error, such as a reset
+// or an EOF from a keep-alive connection the server closed while idle. Unlike
+// [WithRetry], status codes never trigger a retry and attempts follow each
+// other without backoff. Request bodies are replayed the same way, via
+// GetBody. It replaces [WithRetry] and vice versa, the last given wins.
+func WithRetryOnConnectionError(maxRetries int) Option {
+	return func(c *options) error {
+		if maxRetries < 1 {
+			return errors.New("max retries must be at least 1")
+		}
+		c.retry = &retry{
+			maxAttempts:    maxRetries + 1,
+			backoff:        func(int) time.Duration { return 0 },
+			connErrorsOnly: true,
+		}
+		return nil
+	}
+}
+
+// WithSpooledRetryBodies makes request bodies that can't be rewound, such
+// as a stream passed to [http.NewRequest], replayable by [WithRetry] and
+// [WithRetryOnConnectionError]. The body of an idempotent request without
+// GetBody is read in full before the first attempt, kept in memory up to
+// maxMemBytes and spilled to a temp file beyond that, which is removed once
+// the last attempt is done with it. Without a retry option it has no effect.
+func WithSpooledRetryBodies(maxMemBytes int64) Option {
+	return func(c *options) error {
+		if maxMemBytes < 0 {
+			return errors.New("max memory bytes must not be negative")
+		}
+		c.spoolMaxMem = &maxMemBytes
+		return nil
+	}
+}
+
+// WithRetryStatuses sets the response status codes that trigger a retry
+// under [WithRetry]. Default is 502, 503 and 504. [Build] fails if it's
+// given without [WithRetry].
+func WithRetryStatuses(codes ...int) Option {
+	return func(c *options) error {
+		if len(codes) == 0 {
+			return errors.New("retry statuses must not be empty")
+		}
+		c.retryStatuses = codes
+		return nil
+	}
+}
+
+// WithMaxTotalAttempts caps the HTTP round trips made for a single call,
+// such as [Client.Do], counting every redirect followed and every retry of
+// [WithRetry] together, so the two can't multiply into a flood of requests.
+// The round trip that would exceed n fails with [ErrMaxAttemptsExceeded],
+// which isn't retried. The count is carried in the request context, and is
+// checked inside retry, beneath the [PositionOutermost] interceptors.
+func WithMaxTotalAttempts(n int) Option {
+	return func(c *options) error {
+		if n < 1 {
+			return errors.New("max total attempts must be at least 1")
+		}
+		c.maxTotalAttempts = n
+		return nil
+	}
+}
+
+// Position is a point in the transport stack assembled by [Build]
+// at which [WithInterceptor] inserts a RoundTripper. From innermost to
+// outermost the stack is: base transport, [PositionBase] interceptors,
+// User-Agent, [PositionUserAgent] interceptors, throttle and byte throttle,
+// [PositionThrottle] interceptors, retry, [PositionOutermost] interceptors.
+type Position int
+
+// Positions for [WithInterceptor]. Each wraps the named layer, whether or
+// not that layer is enabled.
+const (
+	// PositionBase wraps the base transport, seeing requests last,
+	// after the User-Agent header is set.
+	PositionBase Position = iota
+	// PositionUserAgent wraps the User-Agent layer, inside the throttle.
+	PositionUserAgent
+	// PositionThrottle wraps the throttles, inside retry, so it sees
+	// every retry attempt.
+	PositionThrottle
+	// PositionOutermost wraps the whole stack, seeing each request once.
+	PositionOutermost
+)
+
+// WithInterceptor inserts the RoundTripper returned by rt into the
+// transport stack at position. rt is called once by [Build] with the
+// RoundTripper beneath position. Interceptors at the same position are
+// applied in the order given, so the last one is outermost.
+func WithInterceptor(position Position, rt func(http.RoundTripper) http.RoundTripper) Option {
+	return func(c *options) error {
+		if position < PositionBase || position > PositionOutermost {
+			return fmt.Errorf("unknown interceptor position[%d]", position)
+		}
+		if rt == nil {
+			return errors.New("interceptor must not be nil")
+		}
+		if c.interceptors == nil {
+			c.interceptors = make(map[Position][]func(http.RoundTripper) http.RoundTripper)
+		}
+		c.interceptors[position] = append(c.interceptors[position], rt)
+		return nil
+	}
+}
+
+// intercept wraps transport with the interceptors registered at position.
+func (o *options) intercept(position Position, transport http.RoundTripper) (http.RoundTripper, error) {
+	for _, fn := range o.interceptors[position] {
+		transport = fn(transport)
+		if transport == nil {
+			return nil, fmt.Errorf("interceptor at position[%d] returned nil transport", position)
+		}
+	}
+
+	return transport, nil
+}
+
 // userAgent is an http.RoundTripper, enabling the persistent User-Agent header.
 type userAgent struct {
 	value string
@@ -102,14 +461,230 @@ func (ua userAgent) RoundTrip(r *http.Request) (*http.Response, error) {
 	return ua.base.RoundTrip(cpy)
 }
 
+// retry is an http.RoundTripper, re-sending idempotent
+// requests that fail with a connection error or retryable status.
+type retry struct {
+	maxAttempts    int
+	backoff        func(attempt int) time.Duration
+	statuses       []int
+	connErrorsOnly bool
+	spoolMaxMem    *int64
+	base           http.RoundTripper
+}
+
+func (rt *retry) RoundTrip(r *http.Request) (*http.Response, error) {
+	if rt.spoolMaxMem != nil && idempotent(r.Method) && r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		s, err := newSpool(r.Body, *rt.spoolMaxMem)
+		if err != nil {
+			return nil, fmt.Errorf("retry spooling body: %w", err)
+		}
+		defer s.release()
+
+		body, err := s.open()
+		if err != nil {
+			return nil, fmt.Errorf("retry spooling body: %w", err)
+		}
+
+		r = r.Clone(r.Context())
+		r.Body, r.GetBody = body, s.open
+		r.ContentLength, r.TransferEncoding = s.size, nil
+	}
+
+	if !rt.retryable(r) {
+		return rt.base.RoundTrip(r)
+	}
+
+	for attempt := 1; ; attempt++ {
+		req := r
+		if attempt > 1 && r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("retry rewinding body: %w", err)
+			}
+			req = r.Clone(r.Context())
+			req.Body = body
+		}
+
+		resp, err := rt.base.RoundTrip(req)
+		if attempt == rt.maxAttempts || r.Context().Err() != nil || !rt.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrBodySize))
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(rt.backoff(attempt))
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether r may safely be sent more than once.
+func (rt *retry) retryable(r *http.Request) bool {
+	if !idempotent(r.Method) {
+		return false
+	}
+
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// idempotent reports whether requests with method have the same effect
+// when sent more than once.
+func idempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func (rt *retry) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		if rt.connErrorsOnly {
+			return isConnectionError(err)
+		}
+		// A quiesced throttle rejects every attempt, so retrying would
+		// only delay the fail fast Quiesce promises.
+		if errors.Is(err, throttle.ErrShuttingDown) {
+			return false
+		}
+		return transientError(err)
+	}
+
+	return slices.Contains(rt.statuses, resp.StatusCode)
+}
+
+// isConnectionError reports whether err is a transport-level connection
+// failure that's safe to retry: an EOF, reset, abort or broken pipe on the
+// connection, a dial or accept that ran out of resources for the moment,
+// or a DNS lookup the server failed temporarily.
+func isConnectionError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	if opErr, ok := errors.AsType[*net.OpError](err); ok && (opErr.Op == "dial" || opErr.Op == "accept") {
+		if errors.Is(err, syscall.EAGAIN) {
+			return true
+		}
+	}
+
+	dnsErr, ok := errors.AsType[*net.DNSError](err)
+	return ok && dnsErr.IsTemporary
+}
+
+// transientError reports whether err is a transport failure that may
+// pass on another attempt: a connection error, a refused connection, as
+// while the server restarts, or a timeout, including a DNS lookup that
+// timed out, unlike one that found no such host.
+func transientError(err error) bool {
+	if isConnectionError(err) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+
+	netErr, ok := errors.AsType[net.Error](err)
+	return ok && netErr.Timeout()
+}
+
+// attemptLimit is an http.RoundTripper, failing round trips beyond
+// the budget carried by the request context, see [WithMaxTotalAttempts].
+type attemptLimit struct {
+	base http.RoundTripper
+}
+
+func (al attemptLimit) RoundTrip(r *http.Request) (*http.Response, error) {
+	budget, ok := r.Context().Value(attemptsKey{}).(*attemptBudget)
+	if ok && !budget.take() {
+		if r.Body != nil {
+			_ = r.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: limit of %d", ErrMaxAttemptsExceeded, budget.max)
+	}
+
+	return al.base.RoundTrip(r)
+}
+
 // DoOption is a functional option for [Client.Do].
 type DoOption func(options *doOpts) error
 
 type doOpts struct {
-	responseBody any
-	useJSONNum   bool
+	responseBody        any
+	useJSONNum          bool
+	format              Format
+	beforeSend          beforeSendFn
+	cookies             *[]*http.Cookie
+	meta                *ResponseMeta
+	verifyContentLength bool
+	errorBody           func(body io.Reader) error
 }
 
+// hooks returns the exec hooks configured by the options, capturing the
+// named response headers into the meta, see [WithCaptureResponseHeaders].
+func (o doOpts) hooks(capture []string) execHooks {
+	h := execHooks{beforeSend: o.beforeSend, errorBody: o.errorBody}
+	if o.meta != nil {
+		h.timings = &timingsRecorder{}
+	}
+	if o.cookies != nil || o.meta != nil {
+		cookies, meta, timings := o.cookies, o.meta, h.timings
+		h.onResponse = func(resp *http.Response) {
+			if cookies != nil {
+				*cookies = resp.Cookies()
+			}
+			if meta != nil {
+				*meta = ResponseMeta{
+					StatusCode:    resp.StatusCode,
+					Header:        resp.Header,
+					ContentLength: resp.ContentLength,
+					Timings:       timings.result(),
+					Captured:      captureHeaders(resp.Header, capture),
+				}
+			}
+		}
+	}
+
+	return h
+}
+
+// captureHeaders returns the values of the named headers set in h, or nil
+// if none are.
+func captureHeaders(h http.Header, names []string) http.Header {
+	var captured http.Header
+	for _, name := range names {
+		if values, ok := h[name]; ok {
+			if captured == nil {
+				captured = make(http.Header, len(names))
+			}
+			captured[name] = slices.Clone(values)
+		}
+	}
+
+	return captured
+}
+
+// Format selects the decoder used for a response body.
+type Format int
+
+const (
+	// FormatAuto picks the decoder from the response Content-Type,
+	// using XML for XML media types and JSON otherwise.
+	FormatAuto Format = iota
+	// FormatJSON always decodes the response body as JSON.
+	FormatJSON
+	// FormatXML always decodes the response body as XML.
+	FormatXML
+)
+
 // WithDestination decodes the HTTP response body into bodyTemplate.
 // bodyTemplate must be a pointer.
 func WithDestination[T any](bodyTemplate *T) DoOption {
@@ -120,6 +695,26 @@ func WithDestination[T any](bodyTemplate *T) DoOption {
 	}
 }
 
+// withResponseBody sets an untyped decode destination, used where the
+// destination's type isn't known statically, e.g. [BatchReq.Dest].
+func withResponseBody(dest any) DoOption {
+	return func(opts *doOpts) error {
+		opts.responseBody = dest
+
+		return nil
+	}
+}
+
+// withErrorBody passes fn the whole body of a response with an unexpected
+// status, when it isn't empty, as used by [DoResult].
+func withErrorBody(fn func(body io.Reader) error) DoOption {
+	return func(opts *doOpts) error {
+		opts.errorBody = fn
+
+		return nil
+	}
+}
+
 // WithJSONNumb tells the JSON decoder to use [json.Decoder.UseNumber],
 // preserving number precision as [json.Number] instead of float64.
 func WithJSONNumb() DoOption {
@@ -130,14 +725,134 @@ func WithJSONNumb() DoOption {
 	}
 }
 
+// BatchOption is a functional option for [Client.DoBatch].
+type BatchOption func(options *batchOpts) error
+
+type batchOpts struct {
+	maxConcurrent int
+}
+
+// WithConcurrency caps the number of batch requests in flight at once.
+func WithConcurrency(n int) BatchOption {
+	return func(opts *batchOpts) error {
+		if n <= 0 {
+			return errors.New("concurrency must be greater than zero")
+		}
+
+		opts.maxConcurrent = n
+
+		return nil
+	}
+}
+
+// ListOption is a functional option for [Client.ListDirectory].
+type ListOption func(options *listOpts) error
+
+type listOpts struct {
+	parser download.ListingParser
+}
+
+// WithListingParser replaces the default [download.ParseAutoindex]
+// parser used to extract entries from a directory listing.
+func WithListingParser(parser download.ListingParser) ListOption {
+	return func(opts *listOpts) error {
+		if parser == nil {
+			return errors.New("listing parser must not be nil")
+		}
+
+		opts.parser = parser
+
+		return nil
+	}
+}
+
+// WithFormat overrides the Content-Type based decoder selection used
+// by [WithDestination], forcing the response body to be decoded as f.
+func WithFormat(f Format) DoOption {
+	return func(opts *doOpts) error {
+		if f < FormatAuto || f > FormatXML {
+			return fmt.Errorf("unknown format: %d", f)
+		}
+
+		opts.format = f
+
+		return nil
+	}
+}
+
+// WithBeforeSend registers fn to be called with the request immediately
+// before it's sent, allowing last-moment per-call mutation such as adding
+// a timestamp header. Returning an error aborts the call. Client-level
+// transport layers (e.g. [WithUserAgent]) run afterwards, at transport time.
+func WithBeforeSend(fn func(*http.Request) error) DoOption {
+	return func(opts *doOpts) error {
+		if fn == nil {
+			return errors.New("before send func must not be nil")
+		}
+
+		opts.beforeSend = fn
+
+		return nil
+	}
+}
+
+// WithResponseCookies stores the cookies set by the response into dst.
+// It's populated as soon as the response arrives, so cookies are captured
+// even when the call fails with an [UnexpectedStatusError].
+func WithResponseCookies(dst *[]*http.Cookie) DoOption {
+	return func(opts *doOpts) error {
+		if dst == nil {
+			return errors.New("response cookies destination must not be nil")
+		}
+
+		opts.cookies = dst
+
+		return nil
+	}
+}
+
+// WithResponseMeta stores the response status code, headers,
+// Content-Length, connection [Timings] and the headers named by
+// [WithCaptureResponseHeaders] into dst. Like
+// [WithResponseCookies], it's populated as soon as the response arrives,
+// so it's set on an [UnexpectedStatusError].
+func WithResponseMeta(dst *ResponseMeta) DoOption {
+	return func(opts *doOpts) error {
+		if dst == nil {
+			return errors.New("response meta destination must not be nil")
+		}
+
+		opts.meta = dst
+
+		return nil
+	}
+}
+
+// WithVerifyContentLength checks, after decoding, that the number of body
+// bytes read matches the response's Content-Length, when one is declared.
+// A mismatch fails the call with [ErrContentLengthMismatch], catching
+// truncated bodies that would otherwise decode successfully.
+func WithVerifyContentLength() DoOption {
+	return func(opts *doOpts) error {
+		opts.verifyContentLength = true
+
+		return nil
+	}
+}
+
 // RequestOption is a functional option for [Request].
 type RequestOption func(options *requestOpts) error
 
 type requestOpts struct {
-	body        any
-	contentType *string
-	cookies     []*http.Cookie
-	headers     map[string][]string
+	body           any
+	form           url.Values
+	multipart      *multipartBody
+	file           string
+	contentType    *string
+	cookies        []*http.Cookie
+	headers        map[string][]string
+	accept         string
+	uploadProgress func(sent, total int64)
 }
 
 // WithPayload sets the JSON-encoded request body.
@@ -149,6 +864,68 @@ func WithPayload(body any) RequestOption {
 	}
 }
 
+// WithFormPayload sets the request body to the form-encoded values, with a
+// default Content-Type of "application/x-www-form-urlencoded". It can't be
+// combined with [WithPayload].
+func WithFormPayload(values url.Values) RequestOption {
+	return func(opts *requestOpts) error {
+		if values == nil {
+			return errors.New("form payload must not be nil")
+		}
+
+		opts.form = values
+
+		return nil
+	}
+}
+
+// WithMultipart sets a multipart/form-data request body holding the given
+// form fields followed by the files, and the matching Content-Type with its
+// boundary. It can't be combined with [WithPayload] or [WithFormPayload].
+// The body is streamed from the file readers as the transport sends it
+// rather than buffered, so the request can be sent once only: it isn't
+// retried by [WithRetry], and can't follow redirects that resend the body.
+// The request must be sent, or its Body closed, to release the streaming
+// goroutine.
+func WithMultipart(fields map[string]string, files []FileUpload) RequestOption {
+	return func(opts *requestOpts) error {
+		if len(fields) == 0 && len(files) == 0 {
+			return errors.New("multipart body must have fields or files")
+		}
+		for i, f := range files {
+			if f.FieldName == "" {
+				return fmt.Errorf("file upload[%d] field name must not be empty", i)
+			}
+			if f.Content == nil {
+				return fmt.Errorf("file upload[%d] content must not be nil", i)
+			}
+		}
+
+		opts.multipart = &multipartBody{fields: fields, files: files}
+
+		return nil
+	}
+}
+
+// WithFileUpload streams the file at path as the request body, setting the
+// Content-Length to its size and the Content-Type from its extension, or
+// by sniffing its first 512 bytes, unless set via [WithContentType]. It
+// can't be combined with the other body options. The file is opened by
+// [Request], which fails if it can't be, and closed by the transport once
+// the request is sent, so the request must be sent or its Body closed.
+// It's reopened whenever the body is replayed, e.g. by [WithRetry].
+func WithFileUpload(path string) RequestOption {
+	return func(opts *requestOpts) error {
+		if path == "" {
+			return errors.New("file upload path must not be empty")
+		}
+
+		opts.file = path
+
+		return nil
+	}
+}
+
 // WithContentType overrides the default "application/json" Content-Type header.
 func WithContentType(contentType string) RequestOption {
 	return func(opts *requestOpts) error {
@@ -162,6 +939,20 @@ func WithContentType(contentType string) RequestOption {
 	}
 }
 
+// WithAccept sets the Accept header, indicating the media types
+// the caller is willing to receive.
+func WithAccept(mediaTypes ...string) RequestOption {
+	return func(opts *requestOpts) error {
+		if len(mediaTypes) == 0 {
+			return errors.New("accept requires at least one media type")
+		}
+
+		opts.accept = strings.Join(mediaTypes, ", ")
+
+		return nil
+	}
+}
+
 // WithHeaders adds custom headers to the outgoing request.
 func WithHeaders(headers map[string][]string) RequestOption {
 	return func(opts *requestOpts) error {
@@ -180,11 +971,28 @@ func WithCookies(cookies ...*http.Cookie) RequestOption {
 	}
 }
 
+// WithUploadProgress reports upload progress to fn as the transport reads
+// the request body. total is the body length when known, or -1 otherwise.
+// The progress reader is rebuilt from [http.Request.GetBody] whenever the
+// body is replayed, e.g. on redirects, so sent restarts from zero.
+func WithUploadProgress(fn func(sent, total int64)) RequestOption {
+	return func(opts *requestOpts) error {
+		if fn == nil {
+			return errors.New("upload progress func must not be nil")
+		}
+
+		opts.uploadProgress = fn
+
+		return nil
+	}
+}
+
 // URLOption is a functional option for [URL].
 type URLOption func(options *urlOpts)
 
 type urlOpts struct {
 	queryStrings map[string]string
+	rawQuery     *string
 	port         *int
 }
 
@@ -195,9 +1003,38 @@ func WithQueryStrings(queryKV map[string]string) URLOption {
 	}
 }
 
+// WithRawQuery sets the URL's query string verbatim, bypassing encoding.
+// Use it for APIs with non-standard query formats that reject the
+// output of [url.Values.Encode]. It takes precedence over [WithQueryStrings].
+func WithRawQuery(rawQuery string) URLOption {
+	return func(opts *urlOpts) {
+		opts.rawQuery = &rawQuery
+	}
+}
+
 // WithPort sets the port number on the URL's host.
 func WithPort(port int) URLOption {
 	return func(opts *urlOpts) {
 		opts.port = &port
 	}
 }
+
+// LongPollOptions configures [Client.LongPoll].
+type LongPollOptions struct {
+	// MinBackoff is the initial delay after a failed poll. Default is 1s.
+	MinBackoff time.Duration
+	// MaxBackoff caps the exponentially growing delay. Default is 30s.
+	MaxBackoff time.Duration
+}
+
+func (o LongPollOptions) withDefaults() LongPollOptions {
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+	o.MaxBackoff = max(o.MaxBackoff, o.MinBackoff)
+
+	return o
+}
@@ -16,4 +16,16 @@
 //
 // When the rate limit is exceeded, outbound requests block until a
 // token becomes available or the request context is cancelled.
+//
+// With [WithRespectRetryAfter], a 429 or 503 response carrying a
+// Retry-After header also pauses subsequent requests until it passes.
+// With [WithPerHost], each request host gets its own token bucket.
+// The RoundTripper implements [StatsReporter], counting the requests
+// delayed and the time they spent waiting, and [Quiescer], whose Quiesce
+// makes new and waiting requests fail fast with [ErrShuttingDown] during
+// shutdown.
+//
+// To limit bandwidth rather than request count, [NewByteRoundTripper]
+// throttles the bytes read from all response bodies through a shared
+// token bucket.
 package throttle
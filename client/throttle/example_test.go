@@ -25,3 +25,39 @@ func ExampleNewRoundTripper() {
 	fmt.Println("throttled transport created")
 	// Output: throttled transport created
 }
+
+func ExampleWithRespectRetryAfter() {
+	rt, err := throttle.NewRoundTripper(
+		10, // requests per second
+		5,  // burst capacity
+		func() *slog.Logger { return slog.Default() },
+		http.DefaultTransport,
+		throttle.WithRespectRetryAfter(), // pause on 429/503 with Retry-After
+	)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	_ = &http.Client{Transport: rt}
+
+	fmt.Println("retry-after aware transport created")
+	// Output: retry-after aware transport created
+}
+
+func ExampleNewByteRoundTripper() {
+	rt, err := throttle.NewByteRoundTripper(
+		1<<20,   // bytes per second, shared by all responses
+		256<<10, // burst in bytes
+		http.DefaultTransport,
+	)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	_ = &http.Client{Transport: rt}
+
+	fmt.Println("bandwidth-limited transport created")
+	// Output: bandwidth-limited transport created
+}
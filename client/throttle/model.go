@@ -1,9 +1,14 @@
 package throttle
 
 import (
+	"context"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/time/rate"
 )
@@ -15,6 +20,8 @@ var (
 	ErrWaitingFailed = errors.New("limiter waiting failed")
 	// ErrContextEnded indicates the request context expired before or after the rate-limit wait.
 	ErrContextEnded = errors.New("throttle context ended")
+	// ErrShuttingDown indicates the RoundTripper was quiesced and no longer admits requests.
+	ErrShuttingDown = errors.New("throttle shutting down")
 )
 
 // Config defines the throttler's rate-limiting parameters: requests per second (RPS) and burst capacity.
@@ -23,6 +30,12 @@ type Config struct {
 	Burst int
 }
 
+// ByteConfig defines the byte throttler's parameters: bytes per second and burst size in bytes.
+type ByteConfig struct {
+	BytesPerSec int64
+	Burst       int64
+}
+
 // throttle is an http.RoundTripper, using the time/rate token
 // bucket limiter to restrict outbound calls.
 type throttle struct {
@@ -31,4 +44,81 @@ type throttle struct {
 	burst   int
 	next    http.RoundTripper
 	logFn   func() *slog.Logger
+
+	respectRetryAfter bool
+	mu                sync.Mutex
+	pausedUntil       time.Time
+
+	perHost bool
+	hostsMu sync.Mutex
+	hosts   map[string]*rate.Limiter
+
+	requests atomic.Int64
+	waited   atomic.Int64
+	waitTime atomic.Int64
+
+	quiesceOnce sync.Once
+	quiesced    chan struct{}
+}
+
+// Stats are the counters of a RoundTripper from [NewRoundTripper], for
+// tuning its rps and burst.
+type Stats struct {
+	Requests int64         // Requests that reached the limiter.
+	Waited   int64         // Requests that had to wait for a token.
+	WaitTime time.Duration // Total time spent waiting for tokens.
+}
+
+// StatsReporter is implemented by the RoundTripper returned by
+// [NewRoundTripper], reached via a type assertion:
+//
+//	stats := rt.(throttle.StatsReporter).Stats()
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// Quiescer is implemented by the RoundTripper returned by
+// [NewRoundTripper], reached via a type assertion:
+//
+//	rt.(throttle.Quiescer).Quiesce()
+type Quiescer interface {
+	Quiesce()
+}
+
+// Option is a functional option for [NewRoundTripper].
+type Option func(*throttle)
+
+// WithRespectRetryAfter pauses all requests through the RoundTripper when
+// a 429 or 503 response carries a Retry-After header, in either its
+// delta-seconds or HTTP-date form, until that time has passed. The pause
+// ends early with [ErrContextEnded] if the request context is done.
+func WithRespectRetryAfter() Option {
+	return func(t *throttle) {
+		t.respectRetryAfter = true
+	}
+}
+
+// WithPerHost gives each request host, as in req.URL.Host, its own token
+// bucket with the same rps and burst, created on the host's first request,
+// so a busy host doesn't use up the tokens of the others.
+func WithPerHost() Option {
+	return func(t *throttle) {
+		t.perHost = true
+		t.hosts = make(map[string]*rate.Limiter)
+	}
+}
+
+// byteThrottle is an http.RoundTripper, limiting the combined read
+// throughput of all response bodies with a shared token bucket.
+type byteThrottle struct {
+	limiter *rate.Limiter
+	next    http.RoundTripper
+}
+
+// throttledBody is an io.ReadCloser, waiting on the limiter for
+// every chunk read from the response body.
+type throttledBody struct {
+	rc      io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
 }
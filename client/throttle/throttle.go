@@ -1,9 +1,12 @@
 package throttle
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -13,7 +16,7 @@ import (
 // using a token bucket rate limiter. logFn lazily resolves the logger at request
 // time, making option ordering irrelevant. A nil-returning logFn skips the calls
 // to *Limiter.Allow().
-func NewRoundTripper(rps, burst int, logFn func() *slog.Logger, next http.RoundTripper) (http.RoundTripper, error) {
+func NewRoundTripper(rps, burst int, logFn func() *slog.Logger, next http.RoundTripper, opts ...Option) (http.RoundTripper, error) {
 	if rps <= 0 || burst <= 0 {
 		return nil, fmt.Errorf("rps[%d] and burst[%d] %w", rps, burst, ErrMustNotBeZero)
 	}
@@ -24,6 +27,11 @@ func NewRoundTripper(rps, burst int, logFn func() *slog.Logger, next http.RoundT
 		burst:   burst,
 		next:    next,
 		logFn:   logFn,
+
+		quiesced: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
 	}
 
 	return t, nil
@@ -40,9 +48,19 @@ func (t *throttle) RoundTrip(r *http.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("%w early: %w", ErrContextEnded, err)
 	}
 
+	if t.isQuiesced() {
+		return nil, ErrShuttingDown
+	}
+
+	if err := t.awaitRetryAfter(ctx); err != nil {
+		return nil, err
+	}
+
+	limiter := t.limiterFor(r.URL.Host)
+
 	var waited time.Duration
 	logger := t.logFn()
-	if logger != nil && !t.limiter.Allow() {
+	if logger != nil && !limiter.Allow() {
 		logger.Info("throttle tokens exhausted", "rate", t.rps, "burst", t.burst, "path", r.URL.Path)
 
 		defer func() {
@@ -50,10 +68,16 @@ func (t *throttle) RoundTrip(r *http.Request) (*http.Response, error) {
 		}()
 	}
 
-	start := time.Now()
+	t.requests.Add(1)
 
-	err := t.limiter.Wait(ctx)
-	waited = time.Since(start)
+	waited, err := wait(ctx, limiter, t.quiesced)
+	if waited > 0 {
+		t.waited.Add(1)
+		t.waitTime.Add(int64(waited))
+	}
+	if errors.Is(err, ErrShuttingDown) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrWaitingFailed, err)
 	}
@@ -62,5 +86,214 @@ func (t *throttle) RoundTrip(r *http.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("%w post-wait: %w", ErrContextEnded, err)
 	}
 
-	return t.next.RoundTrip(r)
+	resp, err := t.next.RoundTrip(r)
+	if err == nil && t.respectRetryAfter {
+		t.noteRetryAfter(resp)
+	}
+
+	return resp, err
+}
+
+// Stats returns the throttle's counters so far. It's safe to call
+// concurrently with requests.
+func (t *throttle) Stats() Stats {
+	return Stats{
+		Requests: t.requests.Load(),
+		Waited:   t.waited.Load(),
+		WaitTime: time.Duration(t.waitTime.Load()),
+	}
+}
+
+// Quiesce stops the RoundTripper admitting requests: later calls to
+// RoundTrip, and those still waiting for a token or a Retry-After pause,
+// fail fast with [ErrShuttingDown]. Requests already past the limiter
+// are unaffected. It's safe to call more than once.
+func (t *throttle) Quiesce() {
+	t.quiesceOnce.Do(func() { close(t.quiesced) })
+}
+
+// isQuiesced reports whether [throttle.Quiesce] has been called.
+func (t *throttle) isQuiesced() bool {
+	select {
+	case <-t.quiesced:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait is [rate.Limiter.Wait] for a single token, also returning how long
+// it waited, which is zero if a token was available. It ends early with
+// [ErrShuttingDown] once quiesced is closed.
+func wait(ctx context.Context, limiter *rate.Limiter, quiesced <-chan struct{}) (time.Duration, error) {
+	now := time.Now()
+
+	res := limiter.ReserveN(now, 1)
+	if !res.OK() {
+		return 0, fmt.Errorf("rate: Wait(n=1) exceeds limiter's burst %d", limiter.Burst())
+	}
+
+	delay := res.DelayFrom(now)
+	if delay == 0 {
+		return 0, nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		res.CancelAt(now)
+		return 0, errors.New("rate: Wait(n=1) would exceed context deadline")
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		res.Cancel()
+		return time.Since(now), ctx.Err()
+	case <-quiesced:
+		res.Cancel()
+		return time.Since(now), ErrShuttingDown
+	case <-timer.C:
+		return delay, nil
+	}
+}
+
+// limiterFor returns the token bucket for requests to host, which is
+// the shared one unless [WithPerHost] is set.
+func (t *throttle) limiterFor(host string) *rate.Limiter {
+	if !t.perHost {
+		return t.limiter
+	}
+
+	t.hostsMu.Lock()
+	defer t.hostsMu.Unlock()
+
+	limiter, ok := t.hosts[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(t.rps), t.burst)
+		t.hosts[host] = limiter
+	}
+
+	return limiter
+}
+
+// awaitRetryAfter blocks until any pause set by a Retry-After header has passed.
+func (t *throttle) awaitRetryAfter(ctx context.Context) error {
+	if !t.respectRetryAfter {
+		return nil
+	}
+
+	t.mu.Lock()
+	wait := time.Until(t.pausedUntil)
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%w during retry-after pause: %w", ErrContextEnded, ctx.Err())
+	case <-t.quiesced:
+		return ErrShuttingDown
+	case <-timer.C:
+		return nil
+	}
+}
+
+// noteRetryAfter extends the pause if resp asks the client to back off.
+func (t *throttle) noteRetryAfter(resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+
+	until, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+}
+
+// parseRetryAfter resolves a Retry-After value, given as delta-seconds
+// or an HTTP-date, to an absolute time relative to now.
+func parseRetryAfter(v string, now time.Time) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return time.Time{}, false
+		}
+		return now.Add(time.Duration(secs) * time.Second), true
+	}
+
+	date, err := http.ParseTime(v)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// NewByteRoundTripper returns an http.RoundTripper that limits the aggregate
+// read throughput of response bodies to bytesPerSec, with bursts of up to
+// burstBytes. The limit is shared by every request through the RoundTripper,
+// so it bounds the client's total download bandwidth. Reads are split into
+// chunks of at most burstBytes, so responses larger than the burst still
+// progress, and waits end early with [ErrWaitingFailed] if the request
+// context is done.
+func NewByteRoundTripper(bytesPerSec, burstBytes int64, next http.RoundTripper) (http.RoundTripper, error) {
+	if bytesPerSec <= 0 || burstBytes <= 0 {
+		return nil, fmt.Errorf("bytes per sec[%d] and burst[%d] %w", bytesPerSec, burstBytes, ErrMustNotBeZero)
+	}
+
+	bt := &byteThrottle{
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSec), int(burstBytes)),
+		next:    next,
+	}
+
+	return bt, nil
+}
+
+func (bt *byteThrottle) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := bt.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &throttledBody{rc: resp.Body, ctx: r.Context(), limiter: bt.limiter}
+	}
+
+	return resp, nil
+}
+
+func (tb *throttledBody) Read(p []byte) (int, error) {
+	// WaitN fails for more tokens than the burst, so never read more at once.
+	if burst := tb.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := tb.rc.Read(p)
+	if n > 0 {
+		if werr := tb.limiter.WaitN(tb.ctx, n); werr != nil {
+			return n, fmt.Errorf("%w: %w", ErrWaitingFailed, werr)
+		}
+	}
+
+	return n, err
+}
+
+func (tb *throttledBody) Close() error {
+	return tb.rc.Close()
 }
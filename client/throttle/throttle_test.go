@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -304,6 +305,411 @@ func TestThrottleRoundTripper_Behavior(t *testing.T) {
 	}
 }
 
+func TestThrottleRoundTripper_RetryAfter(t *testing.T) {
+	var hits atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	newRT := func(t *testing.T, opts ...Option) http.RoundTripper {
+		rt, err := NewRoundTripper(100, 100, func() *slog.Logger { return nil }, http.DefaultTransport, opts...)
+		if err != nil {
+			t.Fatalf("creating round tripper: %v", err)
+		}
+		return rt
+	}
+
+	send := func(t *testing.T, rt http.RoundTripper, ctx context.Context) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatalf("creating request: %v", err)
+		}
+		resp, err := rt.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	t.Run("delays next request", func(t *testing.T) {
+		hits.Store(0)
+		rt := newRT(t, WithRespectRetryAfter())
+
+		resp, err := send(t, rt, t.Context())
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("first request: status %v, err %v", resp, err)
+		}
+
+		start := time.Now()
+		resp, err = send(t, rt, t.Context())
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("second request: status %v, err %v", resp, err)
+		}
+
+		if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+			t.Errorf("second request should wait for Retry-After, but took %v", elapsed)
+		}
+	})
+
+	t.Run("pause interrupted by context", func(t *testing.T) {
+		hits.Store(0)
+		rt := newRT(t, WithRespectRetryAfter())
+
+		if _, err := send(t, rt, t.Context()); err != nil {
+			t.Fatalf("first request: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := send(t, rt, ctx)
+		if !errors.Is(err, ErrContextEnded) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("exp ErrContextEnded wrapping DeadlineExceeded, got: %v", err)
+		}
+		if got := hits.Load(); got != 1 {
+			t.Errorf("exp 1 server call, got %d", got)
+		}
+	})
+
+	t.Run("ignored unless opted in", func(t *testing.T) {
+		hits.Store(0)
+		rt := newRT(t)
+
+		if _, err := send(t, rt, t.Context()); err != nil {
+			t.Fatalf("first request: %v", err)
+		}
+
+		start := time.Now()
+		if _, err := send(t, rt, t.Context()); err != nil {
+			t.Fatalf("second request: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("second request should not wait, but took %v", elapsed)
+		}
+	})
+}
+
+func TestThrottleRoundTripper_PerHost(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	hostA := httptest.NewServer(handler)
+	defer hostA.Close()
+	hostB := httptest.NewServer(handler)
+	defer hostB.Close()
+
+	send := func(t *testing.T, rt http.RoundTripper, url string) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("creating request: %v", err)
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("round trip: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// Sends the first request to hostA, using up its only token, then one
+	// to each host concurrently, returning how long each of those took.
+	run := func(t *testing.T, opts ...Option) (elapsedA, elapsedB time.Duration) {
+		rt, err := NewRoundTripper(2, 1, func() *slog.Logger { return nil }, http.DefaultTransport, opts...)
+		if err != nil {
+			t.Fatalf("creating round tripper: %v", err)
+		}
+
+		send(t, rt, hostA.URL)
+
+		var wg sync.WaitGroup
+		start := time.Now()
+		wg.Go(func() {
+			send(t, rt, hostA.URL)
+			elapsedA = time.Since(start)
+		})
+		wg.Go(func() {
+			send(t, rt, hostB.URL)
+			elapsedB = time.Since(start)
+		})
+		wg.Wait()
+
+		return elapsedA, elapsedB
+	}
+
+	t.Run("hosts limited independently", func(t *testing.T) {
+		elapsedA, elapsedB := run(t, WithPerHost())
+
+		if elapsedB > 200*time.Millisecond {
+			t.Errorf("hostB should not wait on hostA's bucket, but took %v", elapsedB)
+		}
+		if elapsedA < 400*time.Millisecond {
+			t.Errorf("hostA should still be limited, but took %v", elapsedA)
+		}
+	})
+
+	t.Run("shared bucket without option", func(t *testing.T) {
+		elapsedA, elapsedB := run(t)
+
+		// Both wait on the one bucket, so the later of them waits for two tokens.
+		if slowest := max(elapsedA, elapsedB); slowest < 900*time.Millisecond {
+			t.Errorf("requests should be serialized on the shared bucket, but slowest took %v", slowest)
+		}
+	})
+}
+
+func TestThrottleRoundTripper_Stats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	rt, err := NewRoundTripper(20, 2, func() *slog.Logger { return nil }, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("creating round tripper: %v", err)
+	}
+
+	reporter, ok := rt.(StatsReporter)
+	if !ok {
+		t.Fatal("round tripper doesn't implement StatsReporter")
+	}
+	if got := reporter.Stats(); got != (Stats{}) {
+		t.Fatalf("expected zero stats before any request, got %+v", got)
+	}
+
+	// A burst of 6 against a bucket of 2: the first 2 pass, the rest wait.
+	const numRequests = 6
+	var wg sync.WaitGroup
+	for range numRequests {
+		wg.Go(func() {
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL, nil)
+			if err != nil {
+				t.Errorf("creating request: %v", err)
+				return
+			}
+			resp, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Errorf("round trip: %v", err)
+				return
+			}
+			resp.Body.Close()
+		})
+	}
+	wg.Wait()
+
+	stats := reporter.Stats()
+	if stats.Requests != numRequests {
+		t.Errorf("Requests = %d, want %d", stats.Requests, numRequests)
+	}
+	if stats.Waited != numRequests-2 {
+		t.Errorf("Waited = %d, want %d", stats.Waited, numRequests-2)
+	}
+	// The waiting requests get tokens 50ms, 100ms, 150ms and 200ms apart.
+	if stats.WaitTime < 400*time.Millisecond {
+		t.Errorf("WaitTime = %v, want at least 400ms", stats.WaitTime)
+	}
+}
+
+func TestThrottleRoundTripper_Quiesce(t *testing.T) {
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	// One token a second, so the second request waits for the limiter.
+	rt, err := NewRoundTripper(1, 1, func() *slog.Logger { return nil }, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("creating round tripper: %v", err)
+	}
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		return rt.RoundTrip(req)
+	}
+
+	inFlight := make(chan error, 1)
+	go func() {
+		resp, err := send()
+		if err == nil {
+			resp.Body.Close()
+		}
+		inFlight <- err
+	}()
+	<-arrived
+
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := send()
+		waiting <- err
+	}()
+	time.Sleep(50 * time.Millisecond) // Let it reach the limiter.
+
+	start := time.Now()
+	rt.(Quiescer).Quiesce()
+	rt.(Quiescer).Quiesce() // Safe to call again.
+
+	if err := <-waiting; !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("waiting request: expected ErrShuttingDown, got: %v", err)
+	}
+	if _, err := send(); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("new request: expected ErrShuttingDown, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("quiesced requests should fail fast, took %v", elapsed)
+	}
+
+	close(release)
+	if err := <-inFlight; err != nil {
+		t.Errorf("in-flight request should complete, got: %v", err)
+	}
+}
+
+func TestNewByteRoundTripper_Validation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		bps   int64
+		burst int64
+	}{
+		{name: "zero rate", bps: 0, burst: 10},
+		{name: "negative rate", bps: -1, burst: 10},
+		{name: "zero burst", bps: 10, burst: 0},
+		{name: "negative burst", bps: 10, burst: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewByteRoundTripper(tc.bps, tc.burst, http.DefaultTransport); !errors.Is(err, ErrMustNotBeZero) {
+				t.Errorf("exp err %v; got: %v", ErrMustNotBeZero, err)
+			}
+		})
+	}
+}
+
+func TestByteThrottleRoundTripper(t *testing.T) {
+	const size = 4 << 10 // 4KB
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, size))
+	}))
+	defer ts.Close()
+
+	get := func(t *testing.T, rt http.RoundTripper, ctx context.Context) (int64, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+		if err != nil {
+			t.Fatalf("creating request: %v", err)
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("round trip: %v", err)
+		}
+		defer resp.Body.Close()
+
+		return io.Copy(io.Discard, resp.Body)
+	}
+
+	t.Run("limits throughput beyond burst", func(t *testing.T) {
+		// 1KB burst, then 8KB/s: the remaining 3KB take ~375ms.
+		rt, err := NewByteRoundTripper(8<<10, 1<<10, http.DefaultTransport)
+		if err != nil {
+			t.Fatalf("creating round tripper: %v", err)
+		}
+
+		start := time.Now()
+		n, err := get(t, rt, t.Context())
+		if err != nil {
+			t.Fatalf("reading body: %v", err)
+		}
+		if n != size {
+			t.Errorf("exp %d bytes, got %d", size, n)
+		}
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+			t.Errorf("exp throttled read of at least 300ms, took %v", elapsed)
+		}
+	})
+
+	t.Run("shared across requests", func(t *testing.T) {
+		// 4KB burst covers one body; the second waits ~500ms for 4KB at 8KB/s.
+		rt, err := NewByteRoundTripper(8<<10, 4<<10, http.DefaultTransport)
+		if err != nil {
+			t.Fatalf("creating round tripper: %v", err)
+		}
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Go(func() {
+				if _, err := get(t, rt, t.Context()); err != nil {
+					t.Errorf("reading body: %v", err)
+				}
+			})
+		}
+		wg.Wait()
+
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Errorf("exp combined reads of at least 400ms, took %v", elapsed)
+		}
+	})
+
+	t.Run("wait interrupted by context", func(t *testing.T) {
+		rt, err := NewByteRoundTripper(100, 100, http.DefaultTransport)
+		if err != nil {
+			t.Fatalf("creating round tripper: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err = get(t, rt, ctx)
+		if !errors.Is(err, ErrWaitingFailed) {
+			t.Errorf("exp ErrWaitingFailed, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("exp read to end with the context, took %v", elapsed)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		value string
+		exp   time.Time
+		expOK bool
+	}{
+		{name: "delta seconds", value: "120", exp: now.Add(2 * time.Minute), expOK: true},
+		{name: "http date", value: "Tue, 02 Jan 2024 03:05:05 GMT", exp: now.Add(time.Minute), expOK: true},
+		{name: "empty", value: ""},
+		{name: "negative", value: "-1"},
+		{name: "garbage", value: "soon"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tc.value, now)
+			if ok != tc.expOK {
+				t.Fatalf("exp ok %v, got %v", tc.expOK, ok)
+			}
+			if ok && !got.Equal(tc.exp) {
+				t.Errorf("exp %v, got %v", tc.exp, got)
+			}
+		})
+	}
+}
+
 func containsDirectContextError(errs []error) bool {
 	for _, err := range errs {
 		if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
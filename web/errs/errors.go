@@ -16,8 +16,13 @@ type Error struct {
 	FuncName string `json:"-"`
 	FileName string `json:"-"`
 	InnerErr bool   `json:"-"`
+
+	pcs []uintptr
 }
 
+// stackDepth caps the number of frames an Error records for [Error.Stack].
+const stackDepth = 5
+
 // New constructs an error based on an app error.
 func New(code int, err error) *Error {
 	pc, filename, line, _ := runtime.Caller(1)
@@ -27,6 +32,28 @@ func New(code int, err error) *Error {
 		Message:  err.Error(),
 		FuncName: runtime.FuncForPC(pc).Name(),
 		FileName: fmt.Sprintf("%s:%d", filename, line),
+		pcs:      callers(),
+	}
+}
+
+// NewStatus constructs an error for the given status code with its
+// standard text as the message, e.g. "Conflict" for 409, for when there's
+// no underlying error to wrap. Codes without a standard text get
+// "status <code>".
+func NewStatus(code int) *Error {
+	pc, filename, line, _ := runtime.Caller(1)
+
+	msg := http.StatusText(code)
+	if msg == "" {
+		msg = fmt.Sprintf("status %d", code)
+	}
+
+	return &Error{
+		Code:     code,
+		Message:  msg,
+		FuncName: runtime.FuncForPC(pc).Name(),
+		FileName: fmt.Sprintf("%s:%d", filename, line),
+		pcs:      callers(),
 	}
 }
 
@@ -41,6 +68,7 @@ func NewInternal(err error) *Error {
 		FuncName: runtime.FuncForPC(pc).Name(),
 		FileName: fmt.Sprintf("%s:%d", filename, line),
 		InnerErr: true,
+		pcs:      callers(),
 	}
 }
 
@@ -49,6 +77,35 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// Stack returns the call stack the error was created on, innermost first,
+// as "func file:line" lines capped at a few frames. Symbolizing is deferred
+// to here, so recording the stack costs little when it's never read.
+func (e *Error) Stack() []string {
+	if len(e.pcs) == 0 {
+		return nil
+	}
+
+	stack := make([]string, 0, len(e.pcs))
+	frames := runtime.CallersFrames(e.pcs)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		if !more {
+			break
+		}
+	}
+
+	return stack
+}
+
+// callers records the stack of the caller of an Error constructor.
+func callers() []uintptr {
+	pcs := make([]uintptr, stackDepth)
+	n := runtime.Callers(3, pcs) // Skip runtime.Callers, callers and the constructor.
+
+	return pcs[:n]
+}
+
 // IsInternal returns true if the error is internal.
 func (e *Error) IsInternal() bool {
 	return e.InnerErr
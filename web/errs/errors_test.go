@@ -31,6 +31,36 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{code: http.StatusConflict, want: "Conflict"},
+		{code: http.StatusNotFound, want: "Not Found"},
+		{code: 599, want: "status 599"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			err := errs.NewStatus(tt.code)
+
+			if err.Code != tt.code {
+				t.Fatalf("Code = %d, want %d", err.Code, tt.code)
+			}
+			if err.Message != tt.want {
+				t.Fatalf("Message = %q, want %q", err.Message, tt.want)
+			}
+			if err.InnerErr {
+				t.Fatal("InnerErr should be false for NewStatus")
+			}
+			if !strings.Contains(err.FileName, "errors_test.go") {
+				t.Fatalf("FileName = %q, want to contain errors_test.go", err.FileName)
+			}
+		})
+	}
+}
+
 func TestNewInternal(t *testing.T) {
 	err := errs.NewInternal(fmt.Errorf("db failure"))
 
@@ -51,6 +81,28 @@ func TestNewInternal(t *testing.T) {
 	}
 }
 
+func TestError_Stack(t *testing.T) {
+	var nest func(depth int) *errs.Error
+	nest = func(depth int) *errs.Error {
+		if depth == 0 {
+			return errs.New(http.StatusBadRequest, fmt.Errorf("deep"))
+		}
+		return nest(depth - 1)
+	}
+
+	stack := nest(10).Stack()
+	if len(stack) != 5 {
+		t.Fatalf("stack frames = %d, want 5: %v", len(stack), stack)
+	}
+	if !strings.Contains(stack[0], "errors_test.go") {
+		t.Fatalf("stack[0] = %q, want the frame calling New", stack[0])
+	}
+
+	if stack := (&errs.Error{Message: "literal"}).Stack(); stack != nil {
+		t.Fatalf("stack = %v, want nil for an Error not built by a constructor", stack)
+	}
+}
+
 func TestError_Error(t *testing.T) {
 	err := errs.New(http.StatusNotFound, fmt.Errorf("not found"))
 
@@ -1,6 +1,7 @@
 package errs_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -21,6 +22,14 @@ func ExampleNew() {
 	// user not found
 }
 
+func ExampleNewStatus() {
+	err := errs.NewStatus(http.StatusConflict)
+
+	b, _ := json.Marshal(err)
+	fmt.Println(string(b))
+	// Output: {"code":409,"message":"Conflict"}
+}
+
 func ExampleNewInternal() {
 	err := errs.NewInternal(fmt.Errorf("db connection lost"))
 
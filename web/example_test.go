@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/adamwoolhether/httper/web"
 	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
 )
 
 // ————————————————————————————————————————————————————————————————————
@@ -109,6 +111,62 @@ func ExampleQueryInt64() {
 	// Output: 8000000000
 }
 
+func ExampleParamOneOf() {
+	r := httptest.NewRequest(http.MethodGet, "/reports/PDF", nil)
+	r.SetPathValue("format", "PDF")
+
+	format, err := web.ParamOneOf(r, "format", "pdf", "csv", "json")
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(format)
+	// Output: pdf
+}
+
+func ExampleQueryEnum() {
+	r := httptest.NewRequest(http.MethodGet, "/items?sort=sideways", nil)
+
+	_, err := web.QueryEnum(r, "sort", []string{"asc", "desc"})
+
+	fmt.Println(err)
+	// Output: [{"field":"sort","error":"query param[sort] must be one of [asc desc]"}]
+}
+
+func ExampleQueryOneOf() {
+	type Order string
+
+	const (
+		Asc  Order = "asc"
+		Desc Order = "desc"
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/items?sort=desc", nil)
+
+	sort, err := web.QueryOneOf(r, "sort", Asc, Desc)
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(sort == Desc)
+	// Output: true
+}
+
+func ExampleQueryPagination() {
+	r := httptest.NewRequest(http.MethodGet, "/items?page=3&size=500", nil)
+
+	p, err := web.QueryPagination(r, web.PaginationDefaults{Size: 20, MaxSize: 100})
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(p.Page, p.Size, p.Offset, p.Limit)
+	// Output: 3 100 200 100
+}
+
 // ————————————————————————————————————————————————————————————————————
 // Decode examples
 // ————————————————————————————————————————————————————————————————————
@@ -149,6 +207,53 @@ func ExampleDecodeAllowUnknownFields() {
 	// Output: alice
 }
 
+func ExampleDecodeWithMaxDepth() {
+	type Input struct {
+		Tags any `json:"tags"`
+	}
+
+	body := strings.NewReader(`{"tags":[[["too deep"]]]}`)
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+
+	var input Input
+	err := web.DecodeWithMaxDepth(r, &input, 3)
+
+	fmt.Println(err)
+	// Output: decode: exceeds max nesting depth of 3
+}
+
+func ExampleDecodeQuery() {
+	type Filters struct {
+		Query  string `query:"q" validate:"required"`
+		Page   int    `query:"page"`
+		Active bool   `query:"active"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/items?q=golang&page=2&active=true", nil)
+
+	var f Filters
+	if err := web.DecodeQuery(r, &f); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(f.Query, f.Page, f.Active)
+	// Output: golang 2 true
+}
+
+func ExampleAbsoluteURL() {
+	// Served by an App built with mux.WithTrustForwardedHeaders, behind
+	// a proxy that sets the X-Forwarded headers.
+	ctx := mux.NewTestContext(mux.WithTestTrustForwardedHeaders())
+
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/orders", nil)
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "api.example.com")
+
+	fmt.Println(web.AbsoluteURL(r, "/orders/42"))
+	// Output: https://api.example.com/orders/42
+}
+
 // ————————————————————————————————————————————————————————————————————
 // Response helper examples
 // ————————————————————————————————————————————————————————————————————
@@ -169,6 +274,19 @@ func ExampleRespondJSON() {
 	// {"status":"ok"}
 }
 
+func ExampleRespondCached() {
+	w := httptest.NewRecorder()
+
+	data := map[string]string{"sha": "9f86d08"}
+	if err := web.RespondCached(context.Background(), w, http.StatusOK, data, 365*24*time.Hour, true); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(w.Header().Get("Cache-Control"))
+	// Output: public, max-age=31536000, immutable
+}
+
 func ExampleRespondError() {
 	w := httptest.NewRecorder()
 
@@ -201,6 +319,33 @@ func ExampleRedirect() {
 	// /new
 }
 
+func ExampleSetPaginationLinks() {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/items?page=2&size=10", nil)
+
+	if err := web.SetPaginationLinks(w, r, 2, 10, 25); err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+
+	fmt.Println(w.Header().Get("Link"))
+	fmt.Println(w.Header().Get("X-Total-Count"))
+	// Output:
+	// <http://example.com/items?page=1&size=10>; rel="first", <http://example.com/items?page=1&size=10>; rel="prev", <http://example.com/items?page=3&size=10>; rel="next", <http://example.com/items?page=3&size=10>; rel="last"
+	// 25
+}
+
+func ExampleTiming() {
+	ctx, _ := web.WithTiming(context.Background())
+
+	_ = web.Timing(ctx).Measure("render", func() error {
+		return nil
+	})
+
+	fmt.Println(strings.HasPrefix(web.Timing(ctx).Header(), "render;dur="))
+	// Output: true
+}
+
 // ————————————————————————————————————————————————————————————————————
 // Validation examples
 // ————————————————————————————————————————————————————————————————————
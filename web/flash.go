@@ -0,0 +1,137 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FlashCookieName is the name of the cookie carrying the message set by
+// [RedirectWithFlash].
+const FlashCookieName = "flash"
+
+// flashTTL is how long a flash message survives unread.
+const flashTTL = 5 * time.Minute
+
+// flashKey is the HMAC key signing flash cookies, random per process
+// until replaced via [SetFlashKey].
+var flashKey atomic.Pointer[[]byte]
+
+func init() {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	flashKey.Store(&key)
+}
+
+// SetFlashKey sets the key signing flash cookies. By default a random key
+// is generated per process, so a message set by one instance can't be read
+// by another, or after a restart; deployments running several instances
+// should share a key. It must be at least 32 bytes.
+func SetFlashKey(key []byte) error {
+	if len(key) < 32 {
+		return errors.New("flash key must be at least 32 bytes")
+	}
+
+	k := append([]byte(nil), key...)
+	flashKey.Store(&k)
+
+	return nil
+}
+
+// RedirectWithFlash is [Redirect], first setting a one-time flash message
+// for the redirected request to read with [Flash]. The message travels in
+// an HTTP-only cookie signed with HMAC-SHA256, so it can't be tampered
+// with, expiring after five minutes if not read.
+func RedirectWithFlash(w http.ResponseWriter, r *http.Request, target string, code int, message string) error {
+	if code < 300 || code > 399 {
+		return Redirect(w, r, target, code)
+	}
+
+	expires := time.Now().Add(flashTTL)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(message)) + "." + strconv.FormatInt(expires.Unix(), 10)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     FlashCookieName,
+		Value:    payload + "." + flashSignature(payload),
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   int(flashTTL / time.Second),
+		Secure:   isHTTPS(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return Redirect(w, r, target, code)
+}
+
+// Flash returns the flash message set by [RedirectWithFlash], reporting
+// whether there was a valid one, and clears its cookie so the message is
+// shown once. Cookies that are expired or fail the signature check are
+// cleared and ignored.
+func Flash(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(FlashCookieName)
+	if err != nil {
+		return "", false
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     FlashCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   isHTTPS(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	payload, sig, ok := cutLast(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(flashSignature(payload))) {
+		return "", false
+	}
+
+	encoded, expiry, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", false
+	}
+
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return "", false
+	}
+
+	message, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+
+	return string(message), true
+}
+
+// flashSignature returns the encoded HMAC-SHA256 of payload.
+func flashSignature(payload string) string {
+	mac := hmac.New(sha256.New, *flashKey.Load())
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
+
+// isHTTPS reports whether r arrived over HTTPS, directly or, with
+// mux.WithTrustForwardedHeaders, via a proxy setting X-Forwarded-Proto.
+func isHTTPS(r *http.Request) bool {
+	return requestScheme(r) == "https"
+}
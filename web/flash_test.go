@@ -0,0 +1,160 @@
+package web_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// flashCookie redirects with message and returns the flash cookie set.
+func flashCookie(t *testing.T, message string) *http.Cookie {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/items", nil)
+
+	if err := web.RedirectWithFlash(w, r, "/items/42", http.StatusSeeOther, message); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if loc := w.Header().Get("Location"); loc != "/items/42" {
+		t.Fatalf("Location = %q, want %q", loc, "/items/42")
+	}
+
+	for _, c := range w.Result().Cookies() {
+		if c.Name == web.FlashCookieName {
+			if !c.HttpOnly || c.MaxAge <= 0 {
+				t.Fatalf("flash cookie = %+v, want HttpOnly and short-lived", c)
+			}
+			return c
+		}
+	}
+
+	t.Fatal("flash cookie not set")
+	return nil
+}
+
+func TestFlash(t *testing.T) {
+	valid := flashCookie(t, "Item saved; 42 → done.")
+
+	tampered := *valid
+	tampered.Value = strings.Replace(valid.Value, valid.Value[:4], "AAAA", 1)
+
+	forged := *valid
+	forged.Value = "SGk.9999999999.c2lnbmF0dXJl"
+
+	tests := map[string]struct {
+		cookie  *http.Cookie
+		want    string
+		wantOK  bool
+		cleared bool
+	}{
+		"valid":     {cookie: valid, want: "Item saved; 42 → done.", wantOK: true, cleared: true},
+		"tampered":  {cookie: &tampered, cleared: true},
+		"forged":    {cookie: &forged, cleared: true},
+		"no cookie": {},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+			if tt.cookie != nil {
+				r.AddCookie(&http.Cookie{Name: tt.cookie.Name, Value: tt.cookie.Value})
+			}
+
+			got, ok := web.Flash(w, r)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("Flash = %q, %t, want %q, %t", got, ok, tt.want, tt.wantOK)
+			}
+
+			var cleared bool
+			for _, c := range w.Result().Cookies() {
+				if c.Name == web.FlashCookieName && c.MaxAge < 0 {
+					cleared = true
+				}
+			}
+			if cleared != tt.cleared {
+				t.Errorf("cookie cleared = %t, want %t", cleared, tt.cleared)
+			}
+		})
+	}
+}
+
+func TestFlash_KeyRotation(t *testing.T) {
+	if err := web.SetFlashKey([]byte("short")); err == nil {
+		t.Fatal("expected error for a short key")
+	}
+
+	if err := web.SetFlashKey(bytes.Repeat([]byte("a"), 32)); err != nil {
+		t.Fatalf("setting key: %v", err)
+	}
+	cookie := flashCookie(t, "hello")
+
+	if err := web.SetFlashKey(bytes.Repeat([]byte("b"), 32)); err != nil {
+		t.Fatalf("setting key: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	if _, ok := web.Flash(httptest.NewRecorder(), r); ok {
+		t.Fatal("expected a cookie signed with another key to be rejected")
+	}
+}
+
+func TestRedirectWithFlash_InvalidCode(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	if err := web.RedirectWithFlash(w, r, "/next", http.StatusOK, "hi"); err == nil {
+		t.Fatal("expected error for a non-3xx code")
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Fatal("expected no cookie for an invalid redirect")
+	}
+}
+
+func TestRedirectWithFlash_Secure(t *testing.T) {
+	tests := map[string]struct {
+		proto string
+		trust bool
+		want  bool
+	}{
+		"plaintext":        {},
+		"forwardedTrusted": {proto: "https", trust: true, want: true},
+		"forwardedSpoofed": {proto: "https"},
+		"forwardedHTTP":    {proto: "http", trust: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/items", nil)
+			if tc.trust {
+				r = r.WithContext(mux.NewTestContext(mux.WithTestTrustForwardedHeaders()))
+			}
+			if tc.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tc.proto)
+			}
+
+			if err := web.RedirectWithFlash(w, r, "/items/42", http.StatusSeeOther, "saved"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			cookies := w.Result().Cookies()
+			if len(cookies) != 1 {
+				t.Fatalf("cookies = %d, want 1", len(cookies))
+			}
+			if cookies[0].Secure != tc.want {
+				t.Errorf("Secure = %t, want %t", cookies[0].Secure, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// standardMethods are the methods defined by RFC 9110 and RFC 5789.
+var standardMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace,
+}
+
+// AllowMethods rejects requests whose method isn't one of methods, such as
+// TRACE or CONNECT, before routing, which [mux.WithMiddleware] ensures.
+// Standard methods get 405 Method Not Allowed and unknown ones 501 Not
+// Implemented, both as a structured error with an Allow header listing
+// methods. As with the ServeMux, allowing GET also allows HEAD. A CORS
+// preflight, an OPTIONS request with an Access-Control-Request-Method
+// header, is let through when the method it asks for is allowed, so
+// [CORS] can answer it without OPTIONS being allowed.
+func AllowMethods(methods ...string) mux.Middleware {
+	allowed := make([]string, 0, len(methods)+1)
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method != "" && !slices.Contains(allowed, method) {
+			allowed = append(allowed, method)
+		}
+	}
+	if slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+
+	allow := strings.Join(allowed, ", ")
+
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if slices.Contains(allowed, r.Method) {
+				return handler(ctx, w, r)
+			}
+
+			if r.Method == http.MethodOptions && slices.Contains(allowed, r.Header.Get("Access-Control-Request-Method")) {
+				return handler(ctx, w, r)
+			}
+
+			code := http.StatusNotImplemented
+			if slices.Contains(standardMethods, r.Method) {
+				code = http.StatusMethodNotAllowed
+			}
+
+			w.Header().Set("Allow", allow)
+
+			return web.RespondError(ctx, w, errs.New(code, fmt.Errorf("method[%s] not allowed", r.Method)))
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,108 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestAllowMethods(t *testing.T) {
+	app := mux.New(mux.WithMiddleware(middleware.AllowMethods("get", http.MethodPost)))
+	ok := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	app.Get("/items", ok)
+	app.Post("/items", ok)
+	app.Handle(http.MethodTrace, "", "/items", ok)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "allowed", method: http.MethodGet, path: "/items", wantCode: http.StatusOK},
+		{name: "head implied by get", method: http.MethodHead, path: "/items", wantCode: http.StatusOK},
+		{name: "allowed post", method: http.MethodPost, path: "/items", wantCode: http.StatusOK},
+		{name: "blocked despite route", method: http.MethodTrace, path: "/items", wantCode: http.StatusMethodNotAllowed},
+		{name: "blocked before routing", method: http.MethodDelete, path: "/missing", wantCode: http.StatusMethodNotAllowed},
+		{name: "unknown method", method: "PURGE", path: "/items", wantCode: http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+
+			app.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK {
+				return
+			}
+
+			if got, want := w.Header().Get("Allow"), "GET, POST, HEAD"; got != want {
+				t.Errorf("Allow = %q, want %q", got, want)
+			}
+
+			var body errs.Error
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding error response: %v", err)
+			}
+			if body.Code != tt.wantCode || body.Message != "method["+tt.method+"] not allowed" {
+				t.Errorf("error body = %+v", body)
+			}
+		})
+	}
+}
+
+func TestAllowMethods_CORSPreflight(t *testing.T) {
+	app := mux.New(mux.WithMiddleware(
+		middleware.AllowMethods(http.MethodGet, http.MethodPost),
+		middleware.CORS([]string{"https://app.example.com"}),
+	))
+	app.Post("/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	tests := []struct {
+		name          string
+		requestMethod string
+		wantCode      int
+		wantCORS      bool
+	}{
+		{name: "preflight for allowed method", requestMethod: http.MethodPost, wantCode: http.StatusNoContent, wantCORS: true},
+		{name: "preflight for blocked method", requestMethod: http.MethodDelete, wantCode: http.StatusMethodNotAllowed},
+		{name: "plain options", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodOptions, "/items", nil)
+			r.Header.Set("Origin", "https://app.example.com")
+			if tt.requestMethod != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+
+			app.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin") != ""; got != tt.wantCORS {
+				t.Errorf("CORS headers set = %t, want %t", got, tt.wantCORS)
+			}
+		})
+	}
+}
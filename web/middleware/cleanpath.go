@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// CleanPathOption is a functional option for [CleanPath].
+type CleanPathOption func(*cleanPathOpts)
+
+type cleanPathOpts struct {
+	rewrite bool
+}
+
+// WithCleanPathRewrite rewrites unclean paths in place for every method,
+// instead of redirecting GET and HEAD requests.
+func WithCleanPathRewrite() CleanPathOption {
+	return func(opts *cleanPathOpts) {
+		opts.rewrite = true
+	}
+}
+
+// CleanPath normalizes the request path with path.Clean, collapsing
+// duplicate slashes and resolving dot segments. A trailing slash on the
+// original path is kept, so "/a//b/" becomes "/a/b/". By default GET and
+// HEAD requests for an unclean path are redirected with 301 Moved
+// Permanently, while other methods are rewritten in place so their body
+// isn't lost. It must run before routing, which [mux.WithMiddleware] ensures.
+func CleanPath(optFns ...CleanPathOption) mux.Middleware {
+	var opts cleanPathOpts
+	for _, opt := range optFns {
+		opt(&opts)
+	}
+
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			cleaned := cleanPath(r.URL.Path)
+			if cleaned == r.URL.Path {
+				return handler(ctx, w, r)
+			}
+
+			if !opts.rewrite && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+				target := url.URL{Path: cleaned, RawQuery: r.URL.RawQuery}
+
+				return web.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+			}
+
+			r2 := r.Clone(ctx)
+			r2.URL.Path = cleaned
+			r2.URL.RawPath = ""
+
+			return handler(ctx, w, r2)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// cleanPath returns the canonical form of p, preserving a trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web/middleware"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		opts         []middleware.CleanPathOption
+		wantCode     int
+		wantLocation string
+		wantPath     string
+	}{
+		{name: "clean", method: http.MethodGet, target: "/a/b", wantCode: http.StatusOK, wantPath: "/a/b"},
+		{name: "clean trailing slash", method: http.MethodGet, target: "/a/b/", wantCode: http.StatusOK, wantPath: "/a/b/"},
+		{name: "double slashes", method: http.MethodGet, target: "//a//b", wantCode: http.StatusMovedPermanently, wantLocation: "/a/b"},
+		{name: "dot segments", method: http.MethodGet, target: "/a/./b/../c", wantCode: http.StatusMovedPermanently, wantLocation: "/a/c"},
+		{name: "keeps trailing slash", method: http.MethodGet, target: "/a//b/", wantCode: http.StatusMovedPermanently, wantLocation: "/a/b/"},
+		{name: "keeps query", method: http.MethodHead, target: "/a//b?x=1", wantCode: http.StatusMovedPermanently, wantLocation: "/a/b?x=1"},
+		{name: "rewrites post", method: http.MethodPost, target: "/a//b", wantCode: http.StatusOK, wantPath: "/a/b"},
+		{name: "rewrite option", method: http.MethodGet, target: "/a/./b", opts: []middleware.CleanPathOption{middleware.WithCleanPathRewrite()}, wantCode: http.StatusOK, wantPath: "/a/b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			mw := middleware.CleanPath(tt.opts...)
+			handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				gotPath = r.URL.Path
+				w.WriteHeader(http.StatusOK)
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+
+			if err := handler(r.Context(), w, r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected Location %q, got %q", tt.wantLocation, got)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("expected handler path %q, got %q", tt.wantPath, gotPath)
+			}
+		})
+	}
+}
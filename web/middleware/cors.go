@@ -4,35 +4,90 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/adamwoolhether/httper/web"
 	"github.com/adamwoolhether/httper/web/errs"
 	"github.com/adamwoolhether/httper/web/mux"
 )
 
-// DefaultAllowHeaders is the default set of headers permitted in
-// cross-origin requests when no custom list is provided to CORS.
+// defaultCORSMaxAge is how long browsers cache a preflight response
+// unless overridden by [CORSConfig].MaxAge.
+const defaultCORSMaxAge = 24 * time.Hour
+
+// CORSConfig configures [CORSWithConfig]. Empty fields take the defaults
+// used by [CORS].
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed, or "*" for all of them.
+	// Entries may contain wildcards, see [CheckOriginFunc].
+	AllowedOrigins []string
+	// AllowedMethods is sent as Access-Control-Allow-Methods. Default is
+	// GET, OPTIONS, PUT, POST, PATCH and DELETE.
+	AllowedMethods []string
+	// AllowedHeaders is sent as Access-Control-Allow-Headers. Default is
+	// Authorization, Content-Type, Accept, X-Requested-With and Cache-Control.
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response, rounded
+	// down to whole seconds. Default is 24 hours; negative omits the header.
+	MaxAge time.Duration
+	// AllowCredentials lets browsers send cookies and auth headers.
+	AllowCredentials bool
+}
 
 // CORS middleware for handling CORS settings.
 // If `*` is given, all origins will be accepted.
-// Sensivle default headers are set, and can be optionally
+// Sensible default headers are set, and can be optionally
 // overridden with the variadic allowedHeaders parameter.
+// Credentials are allowed. See [CORSWithConfig] to configure the rest.
+//
+// An OPTIONS request from an allowed origin is answered with 204 No Content
+// and never reaches the handler. Given to mux.WithMiddleware, CORS runs
+// globally before routing, so preflights succeed for any path, including
+// those with no OPTIONS route registered.
 func CORS(allowedOrigins []string, allowedHeaders ...string) mux.Middleware {
-	defaultHeaders := []string{
-		"Authorization",
-		"Content-Type",
-		"Accept",
-		"X-Requested-With",
-		"Cache-Control",
+	return CORSWithConfig(CORSConfig{
+		AllowedOrigins:   allowedOrigins,
+		AllowedHeaders:   allowedHeaders,
+		AllowCredentials: true,
+	})
+}
+
+// CORSWithConfig is [CORS] with the allowed methods, headers, preflight
+// max age and credentials set by cfg, as reflected in every response to
+// an allowed origin, including preflights.
+func CORSWithConfig(cfg CORSConfig) mux.Middleware {
+	allowedMethods := cfg.AllowedMethods
+	if len(allowedMethods) == 0 {
+		allowedMethods = []string{
+			http.MethodGet,
+			http.MethodOptions,
+			http.MethodPut,
+			http.MethodPost,
+			http.MethodPatch,
+			http.MethodDelete,
+		}
 	}
 
+	allowedHeaders := cfg.AllowedHeaders
 	if len(allowedHeaders) == 0 {
-		allowedHeaders = defaultHeaders
+		allowedHeaders = []string{
+			"Authorization",
+			"Content-Type",
+			"Accept",
+			"X-Requested-With",
+			"Cache-Control",
+		}
 	}
 
-	originAllowed := CheckOriginFunc(allowedOrigins)
+	maxAge := cfg.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultCORSMaxAge
+	}
+
+	originAllowed := CheckOriginFunc(cfg.AllowedOrigins)
+	methods := strings.Join(allowedMethods, ", ")
 	headers := strings.Join(allowedHeaders, ", ")
 
 	m := func(handler mux.Handler) mux.Handler {
@@ -45,9 +100,13 @@ func CORS(allowedOrigins []string, allowedHeaders ...string) mux.Middleware {
 			if originAllowed(origin) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Vary", "Origin")
-				w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS, PUT, POST, PATCH, DELETE")
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-				w.Header().Set("Access-Control-Max-Age", "86400")
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				if cfg.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if maxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.FormatInt(int64(maxAge/time.Second), 10))
+				}
 				w.Header().Set("Access-Control-Allow-Headers", headers)
 			} else {
 				return web.RespondError(ctx, w, errs.New(http.StatusForbidden, fmt.Errorf("CORS origin[%s] not allowed", origin)))
@@ -66,13 +125,16 @@ func CORS(allowedOrigins []string, allowedHeaders ...string) mux.Middleware {
 
 // CheckOriginFunc loads the list of allowed origins, and returns a func that determines
 // if the given origin is valid against the allowable list.
+// An entry's `*` matches one or more host characters, letters, digits, '-' and '.',
+// so "https://*.example.com" allows "https://app.example.com" and "https://a.b.example.com",
+// but neither "https://example.com" nor "https://example.com.evil.com".
+// Matching ignores case.
 func CheckOriginFunc(allowedOrigins []string) func(string) bool {
 	// wildCardCheckFn is a closure to check the given origin against
 	// a list of potential wildcard allowed origins.
 	wildCardCheckFn := func(wildcards []string, origin string) bool {
 		for _, o := range wildcards {
-			matches, err := path.Match(o, origin)
-			if matches && err == nil {
+			if matchOrigin(o, origin) {
 				return true
 			}
 		}
@@ -84,7 +146,11 @@ func CheckOriginFunc(allowedOrigins []string) func(string) bool {
 	// in case the user gives a comma-separated string instead of an array of strings.
 	separated := make([]string, 0)
 	for _, o := range allowedOrigins {
-		separated = append(separated, strings.Split(o, ",")...)
+		for entry := range strings.SplitSeq(o, ",") {
+			if entry = strings.ToLower(strings.TrimSpace(entry)); entry != "" {
+				separated = append(separated, entry)
+			}
+		}
 	}
 
 	allowed := make(map[string]bool)
@@ -105,6 +171,36 @@ func CheckOriginFunc(allowedOrigins []string) func(string) bool {
 	allowAll := allowed["*"]
 
 	return func(origin string) bool {
+		origin = strings.ToLower(origin)
 		return allowAll || allowed[origin] || wildCardCheckFn(wildCardOrigins, origin)
 	}
 }
+
+// matchOrigin reports whether origin matches the wildcard pattern, each `*`
+// standing for a non-empty run of host characters.
+func matchOrigin(pattern, origin string) bool {
+	literal, rest, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == origin
+	}
+
+	origin, ok := strings.CutPrefix(origin, literal)
+	if !ok {
+		return false
+	}
+
+	// Try every run of host characters the wildcard could consume,
+	// shortest first, against the rest of the pattern.
+	for i := 0; i < len(origin) && isHostChar(origin[i]); i++ {
+		if matchOrigin(rest, origin[i+1:]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isHostChar reports whether c may appear in a host name or port.
+func isHostChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.'
+}
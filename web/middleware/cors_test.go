@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/adamwoolhether/httper/web/middleware"
 )
@@ -169,6 +170,46 @@ func TestCheckOriginFunc(t *testing.T) {
 			origin:  "https://sub.example.com",
 			want:    true,
 		},
+		"wildcard nested subdomain": {
+			allowed: []string{"https://*.example.com"},
+			origin:  "https://a.b.example.com",
+			want:    true,
+		},
+		"wildcard bare domain": {
+			allowed: []string{"https://*.example.com"},
+			origin:  "https://example.com",
+			want:    false,
+		},
+		"wildcard empty label": {
+			allowed: []string{"https://*.example.com"},
+			origin:  "https://.example.com",
+			want:    false,
+		},
+		"wildcard spoofed suffix": {
+			allowed: []string{"https://*.example.com"},
+			origin:  "https://example.com.evil.com",
+			want:    false,
+		},
+		"wildcard spoofed userinfo": {
+			allowed: []string{"https://*.example.com"},
+			origin:  "https://evil.com:@x.example.com",
+			want:    false,
+		},
+		"wildcard other scheme": {
+			allowed: []string{"https://*.example.com"},
+			origin:  "http://app.example.com",
+			want:    false,
+		},
+		"wildcard case insensitive": {
+			allowed: []string{"https://*.Example.com"},
+			origin:  "https://App.EXAMPLE.com",
+			want:    true,
+		},
+		"wildcard port": {
+			allowed: []string{"http://localhost:*"},
+			origin:  "http://localhost:3000",
+			want:    true,
+		},
 		"star allow-all": {
 			allowed: []string{"*"},
 			origin:  "https://anything.com",
@@ -179,6 +220,11 @@ func TestCheckOriginFunc(t *testing.T) {
 			origin:  "https://b.com",
 			want:    true,
 		},
+		"comma-separated with spaces": {
+			allowed: []string{"https://a.com, https://*.b.com"},
+			origin:  "https://x.b.com",
+			want:    true,
+		},
 	}
 
 	for name, tc := range tests {
@@ -203,3 +249,93 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+func TestCORSWithConfig_Preflight(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  middleware.CORSConfig
+		want map[string]string
+	}{
+		{
+			name: "custom",
+			cfg: middleware.CORSConfig{
+				AllowedOrigins:   []string{"https://example.com"},
+				AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+				AllowedHeaders:   []string{"Content-Type", "X-Api-Key"},
+				MaxAge:           10 * time.Minute,
+				AllowCredentials: true,
+			},
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "Content-Type, X-Api-Key",
+				"Access-Control-Max-Age":           "600",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name: "defaults without credentials",
+			cfg:  middleware.CORSConfig{AllowedOrigins: []string{"https://example.com"}},
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Methods":     "GET, OPTIONS, PUT, POST, PATCH, DELETE",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type, Accept, X-Requested-With, Cache-Control",
+				"Access-Control-Max-Age":           "86400",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name: "max age omitted",
+			cfg:  middleware.CORSConfig{AllowedOrigins: []string{"*"}, MaxAge: -1},
+			want: map[string]string{
+				"Access-Control-Allow-Origin": "https://example.com",
+				"Access-Control-Max-Age":      "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.CORSWithConfig(tt.cfg)(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				t.Fatal("handler called for a preflight")
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodOptions, "/", nil)
+			r.Header.Set("Origin", "https://example.com")
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			r.Header.Set("Access-Control-Request-Headers", "X-Api-Key")
+
+			if err := handler(r.Context(), w, r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+			}
+			for header, want := range tt.want {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSWithConfig_DisallowedOrigin(t *testing.T) {
+	cors := middleware.CORSWithConfig(middleware.CORSConfig{AllowedOrigins: []string{"https://allowed.com"}})
+	handler := cors(okHandler)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://evil.com")
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// defaultMaxDecompressedSize is the default limit on a decompressed
+// request body, see [WithMaxDecompressedSize].
+const defaultMaxDecompressedSize = 10 << 20
+
+// DecompressRequestOption is a functional option for [DecompressRequest].
+type DecompressRequestOption func(*decompressRequestOpts)
+
+type decompressRequestOpts struct {
+	maxSize int64
+}
+
+// WithMaxDecompressedSize limits the decompressed size of a request body
+// to maxBytes, guarding against decompression bombs, where a small upload
+// expands to fill memory. Default is 10 MiB. Non-positive values are ignored.
+func WithMaxDecompressedSize(maxBytes int64) DecompressRequestOption {
+	return func(opts *decompressRequestOpts) {
+		if maxBytes > 0 {
+			opts.maxSize = maxBytes
+		}
+	}
+}
+
+// DecompressRequest transparently decompresses request bodies sent with
+// "Content-Encoding: gzip", so handlers and [web.Decode] read the plain
+// body. The Content-Encoding and Content-Length headers are removed, as
+// they no longer describe the body. Reading past the size limit fails with
+// an [*http.MaxBytesError]. A body that isn't valid gzip is rejected with
+// 400 Bad Request, and any other encoding with 415 Unsupported Media Type.
+func DecompressRequest(optFns ...DecompressRequestOption) mux.Middleware {
+	opts := decompressRequestOpts{maxSize: defaultMaxDecompressedSize}
+	for _, opt := range optFns {
+		opt(&opts)
+	}
+
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+
+			switch encoding {
+			case "", "identity":
+				return handler(ctx, w, r)
+			case "gzip", "x-gzip":
+			default:
+				return web.RespondError(ctx, w, errs.New(http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %q", encoding)))
+			}
+
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				return web.RespondError(ctx, w, errs.New(http.StatusBadRequest, fmt.Errorf("invalid gzip body: %w", err)))
+			}
+
+			r.Body = http.MaxBytesReader(w, gzipBody{Reader: gz, body: r.Body}, opts.maxSize)
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// gzipBody is an io.ReadCloser reading the decompressed request body,
+// closing both the gzip reader and the original body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	_ = b.Reader.Close()
+	return b.body.Close()
+}
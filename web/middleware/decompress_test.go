@@ -0,0 +1,120 @@
+package middleware_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/middleware"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatalf("compressing: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("closing gzip writer: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecompressRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		opts     []middleware.DecompressRequestOption
+		wantCode int
+		wantBody string
+		wantErr  bool
+	}{
+		{name: "gzip", body: gzipped(t, `{"name":"alice"}`), encoding: "gzip", wantCode: http.StatusOK, wantBody: `{"name":"alice"}`},
+		{name: "x-gzip", body: gzipped(t, "hello"), encoding: "X-Gzip", wantCode: http.StatusOK, wantBody: "hello"},
+		{name: "not encoded", body: []byte("plain"), wantCode: http.StatusOK, wantBody: "plain"},
+		{name: "identity", body: []byte("plain"), encoding: "identity", wantCode: http.StatusOK, wantBody: "plain"},
+		{name: "invalid gzip", body: []byte("not gzip"), encoding: "gzip", wantCode: http.StatusBadRequest},
+		{name: "unsupported encoding", body: []byte("data"), encoding: "br", wantCode: http.StatusUnsupportedMediaType},
+		{name: "over size limit", body: gzipped(t, strings.Repeat("a", 1000)), encoding: "gzip", opts: []middleware.DecompressRequestOption{middleware.WithMaxDecompressedSize(100)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := middleware.DecompressRequest(tt.opts...)
+			handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				if r.Header.Get("Content-Encoding") != "" && !strings.EqualFold(r.Header.Get("Content-Encoding"), "identity") {
+					t.Errorf("expected Content-Encoding removed, got %q", r.Header.Get("Content-Encoding"))
+				}
+
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					return err
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(b)
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			err := handler(r.Context(), w, r)
+			if tt.wantErr {
+				if _, ok := errors.AsType[*http.MaxBytesError](err); !ok {
+					t.Fatalf("expected *http.MaxBytesError, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDecompressRequest_Decode(t *testing.T) {
+	type input struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	mw := middleware.DecompressRequest()
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var in input
+		if err := web.Decode(r, &in); err != nil {
+			return err
+		}
+		return web.RespondJSON(ctx, w, http.StatusOK, in)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipped(t, `{"name":"alice"}`)))
+	r.Header.Set("Content-Encoding", "gzip")
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := w.Body.String(); got != `{"name":"alice"}` {
+		t.Errorf("expected decoded body echoed, got %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -21,23 +22,28 @@ func Errors(log *slog.Logger) mux.Middleware {
 				return nil
 			}
 
-			if fieldErr, ok := errors.AsType[errs.FieldErrors](err); ok {
-				return web.RespondJSON(ctx, w, http.StatusUnprocessableEntity, fieldErr)
-			}
+			return respondErr(ctx, w, log, err, false)
+		}
 
-			appErr, ok := errors.AsType[*errs.Error](err)
-			if !ok { // to catch errs that may have escaped, obscure them from public view.
-				appErr = errs.NewInternal(err)
-			}
+		return h
+	}
 
-			reqLog := log.With("trace_id", mux.GetValues(ctx).TraceID)
-			reqLog.Error(err.Error(), "source_err_file", path.Base(appErr.FileName), "source_err_func", path.Base(appErr.FuncName))
+	return m
+}
 
-			if appErr.InnerErr { // after logging, obscure the internal error from public view.
-				appErr.Message = http.StatusText(appErr.Code)
+// ErrorsWithDev is the same as Errors, but when dev is true, responses are
+// indented and internal errors expose their real message along with the
+// source file and function they were created in and a trimmed stack. When
+// dev is false it behaves exactly like Errors.
+func ErrorsWithDev(log *slog.Logger, dev bool) mux.Middleware {
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := handler(ctx, w, r)
+			if err == nil {
+				return nil
 			}
 
-			return web.RespondJSON(ctx, w, appErr.Code, appErr)
+			return respondErr(ctx, w, log, err, dev)
 		}
 
 		return h
@@ -45,3 +51,70 @@ func Errors(log *slog.Logger) mux.Middleware {
 
 	return m
 }
+
+// devError is the verbose error body sent by ErrorsWithDev in dev mode.
+type devError struct {
+	*errs.Error
+	Internal bool     `json:"internal"`
+	Source   string   `json:"source,omitempty"`
+	Func     string   `json:"func,omitempty"`
+	Stack    []string `json:"stack,omitempty"`
+}
+
+// respondErr logs err and writes the matching error response.
+func respondErr(ctx context.Context, w http.ResponseWriter, log *slog.Logger, err error, dev bool) error {
+	mux.SetError(ctx, err)
+
+	if fieldErr, ok := errors.AsType[errs.FieldErrors](err); ok {
+		if dev {
+			return respondIndented(ctx, w, http.StatusUnprocessableEntity, fieldErr)
+		}
+		return web.RespondJSON(ctx, w, http.StatusUnprocessableEntity, fieldErr)
+	}
+
+	appErr, ok := errors.AsType[*errs.Error](err)
+	if !ok { // to catch errs that may have escaped, obscure them from public view.
+		appErr = errs.NewInternal(err)
+	}
+
+	reqLog := log.With("trace_id", mux.GetValues(ctx).TraceID)
+	reqLog.Error(err.Error(), "source_err_file", path.Base(appErr.FileName), "source_err_func", path.Base(appErr.FuncName))
+
+	if dev {
+		body := devError{
+			Error:    appErr,
+			Internal: appErr.InnerErr,
+			Source:   appErr.FileName,
+			Func:     appErr.FuncName,
+			Stack:    appErr.Stack(),
+		}
+		return respondIndented(ctx, w, appErr.Code, body)
+	}
+
+	if appErr.InnerErr { // after logging, obscure the internal error from public view.
+		public := *appErr
+		public.Message = http.StatusText(appErr.Code)
+		appErr = &public
+	}
+
+	return web.RespondJSON(ctx, w, appErr.Code, appErr)
+}
+
+// respondIndented is web.RespondJSON with human-readable indentation.
+func respondIndented(ctx context.Context, w http.ResponseWriter, statusCode int, data any) error {
+	mux.SetStatusCode(ctx, statusCode)
+
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if _, err = w.Write(jsonData); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -150,3 +150,86 @@ func TestErrors_PlainError(t *testing.T) {
 }
 
 
+
+func TestErrorsWithDev_Internal(t *testing.T) {
+	tests := map[string]struct {
+		dev         bool
+		wantMessage string
+		wantSource  bool
+	}{
+		"dev":  {dev: true, wantMessage: "secret db error", wantSource: true},
+		"prod": {dev: false, wantMessage: http.StatusText(http.StatusInternalServerError)},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, _ := newTestLogger(t)
+			mw := middleware.ErrorsWithDev(log, tc.dev)
+			handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return errs.NewInternal(fmt.Errorf("secret db error"))
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			if err := handler(r.Context(), w, r); err != nil {
+				t.Fatalf("unexpected error from middleware: %v", err)
+			}
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+
+			var m map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if m["message"] != tc.wantMessage {
+				t.Errorf("message = %v, want %q", m["message"], tc.wantMessage)
+			}
+
+			source, _ := m["source"].(string)
+			if tc.wantSource != strings.Contains(source, "errors_test.go") {
+				t.Errorf("source = %q, want present: %v", source, tc.wantSource)
+			}
+
+			stack, _ := m["stack"].([]any)
+			if tc.wantSource {
+				if len(stack) == 0 || len(stack) > 5 {
+					t.Fatalf("stack = %v, want 1 to 5 frames", stack)
+				}
+				if top, _ := stack[0].(string); !strings.Contains(top, "errors_test.go") {
+					t.Errorf("stack[0] = %q, want the frame creating the error", top)
+				}
+			} else if stack != nil {
+				t.Errorf("stack = %v, want none outside dev mode", stack)
+			}
+
+			if indented := strings.Contains(w.Body.String(), "\n  "); indented != tc.dev {
+				t.Errorf("indented = %v, want %v", indented, tc.dev)
+			}
+		})
+	}
+}
+
+func TestErrorsWithDev_FieldErrors(t *testing.T) {
+	log, _ := newTestLogger(t)
+	mw := middleware.ErrorsWithDev(log, true)
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errs.NewFieldsError("email", fmt.Errorf("is required"))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("unexpected error from middleware: %v", err)
+	}
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(w.Body.String(), `"field": "email"`) {
+		t.Errorf("expected indented field error body, got: %s", w.Body.String())
+	}
+}
@@ -1,13 +1,19 @@
 package middleware_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
+	"github.com/adamwoolhether/httper/web"
 	"github.com/adamwoolhether/httper/web/errs"
 	"github.com/adamwoolhether/httper/web/middleware"
 )
@@ -73,6 +79,88 @@ func ExampleCSRF() {
 	// Output: protected
 }
 
+func ExampleCleanPath() {
+	cleanPath := middleware.CleanPath()
+
+	handler := cleanPath(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		fmt.Fprint(w, "routed")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users//42/./posts/", nil)
+	handler(r.Context(), w, r)
+
+	fmt.Println(w.Code, w.Header().Get("Location"))
+	// Output: 301 /users/42/posts/
+}
+
+func ExampleRequireTLS() {
+	requireTLS := middleware.RequireTLS(middleware.WithTLSRedirect())
+
+	handler := requireTLS(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		fmt.Fprint(w, "secure")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/account?tab=keys", nil)
+	handler(r.Context(), w, r)
+
+	fmt.Println(w.Code, w.Header().Get("Location"))
+	// Output: 301 https://example.com/account?tab=keys
+}
+
+func ExampleDecompressRequest() {
+	decompress := middleware.DecompressRequest(middleware.WithMaxDecompressedSize(1 << 20))
+
+	handler := decompress(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(w, string(body))
+		return nil
+	})
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"name":"alice"}`))
+	gz.Close()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/users", &buf)
+	r.Header.Set("Content-Encoding", "gzip")
+	handler(r.Context(), w, r)
+
+	fmt.Println(w.Body.String())
+	// Output: {"name":"alice"}
+}
+
+func ExampleGzip() {
+	compress := middleware.Gzip()
+
+	handler := compress(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"alice"}`)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	handler(r.Context(), w, r)
+
+	gz, _ := gzip.NewReader(w.Body)
+	body, _ := io.ReadAll(gz)
+
+	fmt.Println(w.Header().Get("Content-Encoding"))
+	fmt.Println(string(body))
+	// Output:
+	// gzip
+	// {"name":"alice"}
+}
+
 // ————————————————————————————————————————————————————————————————————
 // Request lifecycle middleware examples
 // ————————————————————————————————————————————————————————————————————
@@ -94,6 +182,23 @@ func ExampleLogger() {
 	// Output: logged
 }
 
+func ExampleSlowRequest() {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	slow := middleware.SlowRequest(500*time.Millisecond, log)
+
+	handler := slow(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		fmt.Fprint(w, "served")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/test", nil)
+	handler(r.Context(), w, r)
+
+	fmt.Println(w.Body.String())
+	// Output: served
+}
+
 func ExampleErrors() {
 	log := slog.New(slog.NewTextHandler(io.Discard, nil))
 	errMW := middleware.Errors(log)
@@ -113,6 +218,33 @@ func ExampleErrors() {
 	// {"code":404,"message":"item not found"}
 }
 
+func ExampleErrorsWithDev() {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	mw := middleware.ErrorsWithDev(logger, true)
+
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errs.NewInternal(fmt.Errorf("db connection refused"))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	handler(context.Background(), w, r)
+
+	var body struct {
+		Message  string `json:"message"`
+		Internal bool   `json:"internal"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+
+	fmt.Println(w.Code)
+	fmt.Println(body.Message, body.Internal)
+	// Output:
+	// 500
+	// db connection refused true
+}
+
 func ExamplePanics() {
 	panics := middleware.Panics()
 
@@ -128,3 +260,57 @@ func ExamplePanics() {
 	fmt.Println(w.Body.String())
 	// Output: safe
 }
+
+// ————————————————————————————————————————————————————————————————————
+// ResponseSchema examples
+// ————————————————————————————————————————————————————————————————————
+
+func ExampleResponseSchema() {
+	schema := []byte(`{"type":"object","required":["id"],"properties":{"id":{"type":"integer"}}}`)
+
+	mw := middleware.ResponseSchema(schema, middleware.WithSchemaMismatchFunc(func(ctx context.Context, r *http.Request, err error) {
+		fmt.Println("mismatch:", err)
+	}))
+
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"alice"}`)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	handler(context.Background(), w, r)
+
+	fmt.Println(w.Body.String())
+	// Output:
+	// mismatch: $: missing required property "id"
+	// {"name":"alice"}
+}
+
+// ————————————————————————————————————————————————————————————————————
+// ServerTiming examples
+// ————————————————————————————————————————————————————————————————————
+
+func ExampleServerTiming() {
+	mw := middleware.ServerTiming()
+
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		timing := web.Timing(ctx)
+
+		timing.Start("db")
+		// ... query the database ...
+		timing.Stop("db")
+
+		return web.RespondJSON(ctx, w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	handler(context.Background(), w, r)
+
+	fmt.Println(strings.HasPrefix(w.Header().Get("Server-Timing"), "db;dur="))
+	// Output: true
+}
@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// Gzip compresses response bodies for clients that send
+// "Accept-Encoding: gzip", setting Content-Encoding and dropping any
+// Content-Length. "Vary: Accept-Encoding" is added to every response so
+// caches keep both forms. Bodies that are already compressed are sent as
+// is, as are those with a Content-Encoding set by the handler, responses
+// without a body, and HEAD requests. The Content-Type decides, sniffed
+// from the first write when the handler didn't set one. Flushes, e.g. for
+// streamed responses, flush the compressed bytes written so far.
+func Gzip() mux.Middleware {
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				return handler(ctx, w, r)
+			}
+
+			gw := &gzipWriter{ResponseWriter: w}
+
+			err := handler(ctx, gw, r)
+
+			// A failed close means the client write failed; there's no
+			// response left to send, so it's not the handler's error.
+			_ = gw.close()
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows gzip,
+// naming it, or else "*", without a q-value of 0. An explicit gzip entry
+// takes precedence over "*".
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for part := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = v
+			}
+		}
+
+		if coding == "gzip" {
+			gzipQ = q
+		} else {
+			anyQ = q
+		}
+	}
+
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+
+	return anyQ > 0
+}
+
+// incompressibleTypes are media types whose content is already compressed.
+var incompressibleTypes = map[string]bool{
+	"application/gzip":             true,
+	"application/x-gzip":           true,
+	"application/zip":              true,
+	"application/zstd":             true,
+	"application/x-bzip2":          true,
+	"application/x-xz":             true,
+	"application/x-7z-compressed":  true,
+	"application/x-rar-compressed": true,
+	"application/vnd.rar":          true,
+	"font/woff":                    true,
+	"font/woff2":                   true,
+}
+
+// compressible reports whether a response with the given status code and
+// headers should be gzipped.
+func compressible(code int, h http.Header) bool {
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return true
+	}
+
+	switch {
+	case mt == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mt, "image/"), strings.HasPrefix(mt, "video/"), strings.HasPrefix(mt, "audio/"):
+		return false
+	}
+
+	return !incompressibleTypes[mt]
+}
+
+// gzipWriter is an http.ResponseWriter, compressing the body when the
+// response turns out to be compressible as its headers are written. The
+// headers of a compressible response are held back until the first body
+// bytes, so one without a body is sent as is rather than as an empty
+// gzip stream.
+type gzipWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	code        int
+	compress    bool
+	wroteHeader bool
+}
+
+func (gw *gzipWriter) WriteHeader(code int) {
+	if gw.wroteHeader || code < http.StatusOK {
+		if !gw.pending() {
+			gw.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+
+	gw.wroteHeader = true
+	gw.code = code
+	gw.compress = compressible(code, gw.Header())
+	if !gw.compress {
+		gw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (gw *gzipWriter) Write(p []byte) (int, error) {
+	if !gw.wroteHeader {
+		if gw.Header().Get("Content-Type") == "" {
+			gw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		gw.WriteHeader(http.StatusOK)
+	}
+
+	if !gw.compress {
+		return gw.ResponseWriter.Write(p)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	gw.startGzip()
+	return gw.gz.Write(p)
+}
+
+// Flush flushes the compressed bytes written so far to the client.
+func (gw *gzipWriter) Flush() {
+	if !gw.wroteHeader {
+		gw.WriteHeader(http.StatusOK)
+	}
+	if gw.compress {
+		gw.startGzip()
+		_ = gw.gz.Flush()
+	}
+
+	_ = http.NewResponseController(gw.ResponseWriter).Flush()
+}
+
+// pending reports whether the headers of a compressible response are
+// still held back, waiting on the first body bytes.
+func (gw *gzipWriter) pending() bool {
+	return gw.compress && gw.gz == nil
+}
+
+// startGzip sends the held back headers of a compressible response,
+// switching it to gzip, unless already sent.
+func (gw *gzipWriter) startGzip() {
+	if !gw.pending() {
+		return
+	}
+
+	gw.Header().Set("Content-Encoding", "gzip")
+	gw.Header().Del("Content-Length")
+	gw.ResponseWriter.WriteHeader(gw.code)
+	gw.gz = gzip.NewWriter(gw.ResponseWriter)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by
+// http.ResponseController.
+func (gw *gzipWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// close writes the gzip footer, if the body was compressed, or the held
+// back headers, uncompressed, of a response without a body.
+func (gw *gzipWriter) close() error {
+	if gw.pending() {
+		gw.ResponseWriter.WriteHeader(gw.code)
+		return nil
+	}
+	if gw.gz == nil {
+		return nil
+	}
+
+	return gw.gz.Close()
+}
@@ -0,0 +1,219 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestGzip(t *testing.T) {
+	const payload = `{"name":"alice","tags":["a","b","c"]}`
+
+	jsonHandler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err := io.WriteString(w, payload)
+		return err
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		handler        mux.Handler
+		wantGzip       bool
+		wantBody       string
+	}{
+		{name: "gzip accepted", method: http.MethodGet, acceptEncoding: "gzip, deflate", handler: jsonHandler, wantGzip: true, wantBody: payload},
+		{name: "wildcard accepted", method: http.MethodGet, acceptEncoding: "*", handler: jsonHandler, wantGzip: true, wantBody: payload},
+		{name: "no accept encoding", method: http.MethodGet, handler: jsonHandler, wantBody: payload},
+		{name: "gzip refused", method: http.MethodGet, acceptEncoding: "gzip;q=0, br", handler: jsonHandler, wantBody: payload},
+		{name: "gzip refused over wildcard", method: http.MethodGet, acceptEncoding: "*;q=1, gzip;q=0", handler: jsonHandler, wantBody: payload},
+		{name: "gzip accepted over wildcard", method: http.MethodGet, acceptEncoding: "*;q=0, gzip", handler: jsonHandler, wantGzip: true, wantBody: payload},
+		{name: "head", method: http.MethodHead, acceptEncoding: "gzip", handler: jsonHandler, wantBody: payload},
+		{
+			name:           "already compressed type",
+			method:         http.MethodGet,
+			acceptEncoding: "gzip",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Content-Type", "image/png")
+				_, err := io.WriteString(w, "\x89PNG")
+				return err
+			},
+			wantBody: "\x89PNG",
+		},
+		{
+			name:           "handler set encoding",
+			method:         http.MethodGet,
+			acceptEncoding: "gzip",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Content-Encoding", "br")
+				_, err := io.WriteString(w, "brotli")
+				return err
+			},
+			wantBody: "brotli",
+		},
+		{
+			name:           "sniffed content type",
+			method:         http.MethodGet,
+			acceptEncoding: "gzip",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				_, err := io.WriteString(w, "<html><body>hi</body></html>")
+				return err
+			},
+			wantGzip: true,
+			wantBody: "<html><body>hi</body></html>",
+		},
+		{
+			name:           "empty body",
+			method:         http.MethodGet,
+			acceptEncoding: "gzip",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				return nil
+			},
+		},
+		{
+			name:           "no content",
+			method:         http.MethodDelete,
+			acceptEncoding: "gzip",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.RespondJSON(ctx, w, http.StatusNoContent, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := middleware.Gzip()(tc.handler)
+
+			ctx := mux.NewTestContext()
+			r := httptest.NewRequest(tc.method, "/", nil).WithContext(ctx)
+			if tc.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+
+			if err := handler(ctx, w, r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want %q", got, "Accept-Encoding")
+			}
+
+			body := w.Body.String()
+			if tc.wantGzip {
+				if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+					t.Fatalf("Content-Encoding = %q, want gzip", got)
+				}
+				if got := w.Header().Get("Content-Length"); got != "" {
+					t.Errorf("Content-Length = %q, want none", got)
+				}
+
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("gzip.NewReader: %v", err)
+				}
+				decoded, err := io.ReadAll(gz)
+				if err != nil {
+					t.Fatalf("reading gzip body: %v", err)
+				}
+				body = string(decoded)
+			} else if got := w.Header().Get("Content-Encoding"); got == "gzip" {
+				t.Fatalf("Content-Encoding = gzip, want uncompressed")
+			}
+
+			if body != tc.wantBody {
+				t.Errorf("body = %q, want %q", body, tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestGzip_StatusCode(t *testing.T) {
+	handler := middleware.Gzip()(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.RespondJSON(ctx, w, http.StatusCreated, map[string]string{"id": "42"})
+	})
+
+	ctx := mux.NewTestContext()
+	r := httptest.NewRequest(http.MethodPost, "/items", nil).WithContext(ctx)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	if err := handler(ctx, w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("code = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if got := mux.GetValues(ctx).StatusCode; got != http.StatusCreated {
+		t.Errorf("tracked status = %d, want %d", got, http.StatusCreated)
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+	if got, want := string(decoded), `{"id":"42"}`; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestGzip_Flush(t *testing.T) {
+	handler := middleware.Gzip()(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		// Flushing before any body still commits to a gzip stream.
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "data: hi\n\n")
+		return err
+	})
+
+	ctx := mux.NewTestContext()
+	r := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	if err := handler(ctx, w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+	if got, want := string(decoded), "data: hi\n\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
@@ -2,22 +2,32 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/adamwoolhether/httper/web/errs"
 	"github.com/adamwoolhether/httper/web/mux"
 )
 
 // Logger logs the start and completion of each request, including
-// method, path, remote address, status code, and elapsed time.
+// method, path, remote address, status code, and elapsed time, along with
+// the ID set by the RequestID middleware, if any. When the handler fails,
+// the completion line also carries the error, whether it was returned
+// directly or already handled by the Errors middleware. Logs hold the
+// real message; obscuring internal errors only applies to the response,
+// so error_internal flags those that clients don't see.
 func Logger(log *slog.Logger) mux.Middleware {
 	m := func(handler mux.Handler) mux.Handler {
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 			v := mux.GetValues(ctx)
 
 			reqLog := log.With("trace_id", v.TraceID)
+			if v.RequestID != "" {
+				reqLog = reqLog.With("request_id", v.RequestID)
+			}
 
 			path := r.URL.Path
 			if r.URL.RawQuery != "" {
@@ -28,7 +38,17 @@ func Logger(log *slog.Logger) mux.Middleware {
 
 			err := handler(ctx, w, r)
 
-			reqLog.Info("request completed", "method", r.Method, "path", path, "remoteaddr", r.RemoteAddr, "statusCode", v.StatusCode, "since", time.Since(v.Now).String())
+			attrs := []any{"method", r.Method, "path", path, "remoteaddr", r.RemoteAddr, "statusCode", v.StatusCode, "since", time.Since(v.Now).String()}
+
+			logErr := err
+			if logErr == nil {
+				logErr = v.Err
+			}
+			if logErr != nil {
+				attrs = append(attrs, errAttrs(logErr)...)
+			}
+
+			reqLog.Info("request completed", attrs...)
 
 			return err
 		}
@@ -38,3 +58,19 @@ func Logger(log *slog.Logger) mux.Middleware {
 
 	return m
 }
+
+// errAttrs describes err for the completion log line, classifying it
+// the same way the Errors middleware does when responding.
+func errAttrs(err error) []any {
+	attrs := []any{"error", err.Error()}
+
+	if _, ok := errors.AsType[errs.FieldErrors](err); ok {
+		return append(attrs, "error_code", http.StatusUnprocessableEntity, "error_internal", false)
+	}
+
+	if appErr, ok := errors.AsType[*errs.Error](err); ok {
+		return append(attrs, "error_code", appErr.Code, "error_internal", appErr.InnerErr)
+	}
+
+	return append(attrs, "error_code", http.StatusInternalServerError, "error_internal", true)
+}
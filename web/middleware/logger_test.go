@@ -3,6 +3,7 @@ package middleware_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/adamwoolhether/httper/web/errs"
 	"github.com/adamwoolhether/httper/web/middleware"
 	"github.com/adamwoolhether/httper/web/mux"
 )
@@ -94,6 +96,78 @@ func TestLogger_StatusCode(t *testing.T) {
 	}
 }
 
+func TestLogger_HandlerError(t *testing.T) {
+	tests := map[string]struct {
+		withErrors bool
+		err        error
+		want       []string
+	}{
+		"handled by Errors": {
+			withErrors: true,
+			err:        errs.NewInternal(errors.New("db down")),
+			want:       []string{`error="db down"`, "error_code=500", "error_internal=true"},
+		},
+		"returned directly": {
+			err:  errs.NewFieldsError("email", errors.New("required")),
+			want: []string{"error_code=422", "error_internal=false"},
+		},
+		"unclassified": {
+			err:  errors.New("boom"),
+			want: []string{"error=boom", "error_code=500", "error_internal=true"},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			log, buf := newTestLogger(t)
+
+			var handler mux.Handler = func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			}
+			if tt.withErrors {
+				handler = middleware.Errors(log)(handler)
+			}
+			handler = middleware.Logger(log)(handler)
+
+			ctx := mux.NewTestContext()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+			handler(ctx, w, r)
+
+			var completed string
+			for line := range strings.Lines(buf.String()) {
+				if strings.Contains(line, "request completed") {
+					completed = line
+				}
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(completed, want) {
+					t.Errorf("expected %s in completion line: %s", want, completed)
+				}
+			}
+		})
+	}
+}
+
+func TestLogger_NoError(t *testing.T) {
+	log, buf := newTestLogger(t)
+
+	handler := middleware.Logger(log)(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+
+	ctx := mux.NewTestContext()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	handler(ctx, w, r)
+
+	if strings.Contains(buf.String(), "error") {
+		t.Fatalf("expected no error fields in log output: %s", buf.String())
+	}
+}
+
 func newTestLogger(t *testing.T) (*slog.Logger, *bytes.Buffer) {
 	var buf bytes.Buffer
 	log := slog.New(slog.NewTextHandler(&buf, nil))
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// MaxBodySize limits request bodies to maxBytes, wrapping them in an
+// [http.MaxBytesReader] so reading past the limit, e.g. in [web.Decode],
+// fails instead of filling memory. A request whose Content-Length already
+// exceeds the limit is rejected with 413 Request Entity Too Large before
+// the handler runs. When the handler hits the limit while reading and
+// returns an error, however it wraps it, that error is replaced with a 413
+// [errs.Error] for the Errors middleware to render. Non-positive values
+// disable the limit.
+func MaxBodySize(maxBytes int64) mux.Middleware {
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if maxBytes <= 0 || r.Body == nil || r.Body == http.NoBody {
+				return handler(ctx, w, r)
+			}
+
+			tooLarge := fmt.Errorf("request body exceeds %d bytes", maxBytes)
+
+			if r.ContentLength > maxBytes {
+				return web.RespondError(ctx, w, errs.New(http.StatusRequestEntityTooLarge, tooLarge))
+			}
+
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, maxBytes)}
+			r.Body = body
+
+			err := handler(ctx, w, r)
+			if err != nil && body.exceeded {
+				return errs.New(http.StatusRequestEntityTooLarge, tooLarge)
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// limitedBody is an io.ReadCloser, recording whether
+// reads failed on the limit of its http.MaxBytesReader.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	n, err := lb.ReadCloser.Read(p)
+	if _, ok := errors.AsType[*http.MaxBytesError](err); ok {
+		lb.exceeded = true
+	}
+
+	return n, err
+}
@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	decodeHandler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		var p payload
+		if err := web.Decode(r, &p); err != nil {
+			return errs.New(http.StatusBadRequest, err)
+		}
+		return web.RespondJSON(ctx, w, http.StatusOK, p)
+	}
+
+	log, _ := newTestLogger(t)
+
+	app := mux.New(mux.WithMiddleware(middleware.Errors(log), middleware.MaxBodySize(32)))
+	app.Post("/app", decodeHandler)
+
+	route := mux.New(mux.WithMiddleware(middleware.Errors(log)))
+	route.Post("/route", decodeHandler, middleware.MaxBodySize(32))
+
+	large := `{"name":"` + strings.Repeat("a", 64) + `"}`
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		path     string
+		body     string
+		chunked  bool
+		wantCode int
+	}{
+		{name: "within limit", handler: app, path: "/app", body: `{"name":"alice"}`, wantCode: http.StatusOK},
+		{name: "content length over limit", handler: app, path: "/app", body: large, wantCode: http.StatusRequestEntityTooLarge},
+		{name: "streamed over limit", handler: app, path: "/app", body: large, chunked: true, wantCode: http.StatusRequestEntityTooLarge},
+		{name: "per route over limit", handler: route, path: "/route", body: large, chunked: true, wantCode: http.StatusRequestEntityTooLarge},
+		{name: "invalid json within limit", handler: app, path: "/app", body: `{"name":`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.chunked {
+				r.Body = io.NopCloser(strings.NewReader(tt.body))
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusRequestEntityTooLarge {
+				return
+			}
+
+			var body errs.Error
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding error response: %v", err)
+			}
+			if body.Code != http.StatusRequestEntityTooLarge || body.Message != "request body exceeds 32 bytes" {
+				t.Errorf("error body = %+v, want 413 with the limit", body)
+			}
+		})
+	}
+}
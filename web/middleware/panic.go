@@ -16,6 +16,7 @@ func Panics() mux.Middleware {
 			defer func() {
 				if rec := recover(); rec != nil {
 					trace := debug.Stack()
+					mux.RecordPanic(ctx)
 					err = fmt.Errorf("PANIC [%v] TRACE[%s]", rec, string(trace))
 				}
 			}()
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// RequestIDHeader is the header RequestID reads and writes.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen caps the length of a request ID taken from a client.
+const maxRequestIDLen = 128
+
+// RequestID honors the request's X-Request-ID header, or generates a UUID
+// when it's absent or invalid, and echoes it on the response. Handlers
+// read it with [mux.GetRequestID], and the Logger middleware adds it to
+// its lines as request_id. A supplied ID must be at most 128 printable
+// ASCII characters, so it can't inject into logs or headers.
+func RequestID() mux.Middleware {
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = uuid.New().String()
+			}
+
+			mux.SetRequestID(ctx, id)
+			w.Header().Set(RequestIDHeader, id)
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// validRequestID reports whether id is a non-empty run of printable,
+// non-space ASCII no longer than maxRequestIDLen.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
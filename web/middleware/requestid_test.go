@@ -0,0 +1,80 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestRequestID(t *testing.T) {
+	testCases := []struct {
+		name      string
+		header    string
+		wantID    string
+		generated bool
+	}{
+		{name: "supplied", header: "req-abc-123", wantID: "req-abc-123"},
+		{name: "absent", generated: true},
+		{name: "contains space", header: "req 123", generated: true},
+		{name: "too long", header: strings.Repeat("a", 129), generated: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotID string
+			handler := middleware.RequestID()(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				gotID = mux.GetRequestID(ctx)
+				w.WriteHeader(http.StatusOK)
+				return nil
+			})
+
+			ctx := mux.NewTestContext(mux.WithTestTraceID("trace-1"))
+			r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+			if tc.header != "" {
+				r.Header.Set("X-Request-ID", tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			if err := handler(ctx, w, r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.generated {
+				if _, err := uuid.Parse(gotID); err != nil {
+					t.Fatalf("expected a generated UUID, got %q", gotID)
+				}
+			} else if gotID != tc.wantID {
+				t.Fatalf("GetRequestID = %q, want %q", gotID, tc.wantID)
+			}
+			if got := w.Header().Get("X-Request-ID"); got != gotID {
+				t.Fatalf("response X-Request-ID = %q, want %q", got, gotID)
+			}
+		})
+	}
+}
+
+func TestRequestID_Logged(t *testing.T) {
+	log, buf := newTestLogger(t)
+
+	// Passed after Logger, RequestID is still sorted to run first.
+	app := mux.New(mux.WithMiddleware(middleware.Logger(log), middleware.RequestID()))
+	app.Get("/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Request-ID", "req-logged")
+	app.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got := strings.Count(buf.String(), "request_id=req-logged"); got != 2 {
+		t.Fatalf("expected request_id on both log lines, got %d in: %s", got, buf.String())
+	}
+}
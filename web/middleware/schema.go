@@ -0,0 +1,403 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// ResponseSchemaOption is a functional option for [ResponseSchema].
+type ResponseSchemaOption func(*responseSchemaOpts)
+
+type responseSchemaOpts struct {
+	log        *slog.Logger
+	onMismatch func(ctx context.Context, r *http.Request, err error)
+	fail       bool
+}
+
+// WithSchemaLogger sets the logger mismatches are logged to at Error
+// level. Default is slog.Default().
+func WithSchemaLogger(log *slog.Logger) ResponseSchemaOption {
+	return func(opts *responseSchemaOpts) {
+		opts.log = log
+	}
+}
+
+// WithSchemaMismatchFunc calls fn with the validation error of each
+// mismatch instead of logging it, e.g. to fail a test with t.Error.
+func WithSchemaMismatchFunc(fn func(ctx context.Context, r *http.Request, err error)) ResponseSchemaOption {
+	return func(opts *responseSchemaOpts) {
+		opts.onMismatch = fn
+	}
+}
+
+// WithSchemaFailOnMismatch discards a response that doesn't conform and
+// returns the mismatch as an internal error instead, for [Errors] to
+// answer with 500, so contract drift can't go unnoticed in tests. The
+// mismatch is still logged, or passed to [WithSchemaMismatchFunc].
+func WithSchemaFailOnMismatch() ResponseSchemaOption {
+	return func(opts *responseSchemaOpts) {
+		opts.fail = true
+	}
+}
+
+// ResponseSchema validates successful JSON responses against the given
+// JSON Schema, intended for contract testing in test and staging
+// environments. The response is buffered, validated, and then written
+// unchanged to the client; a 2xx JSON body that doesn't conform is
+// logged, or handled as configured by the options. It panics if the
+// schema can't be parsed.
+//
+// Only a subset of JSON Schema is supported: type, enum, properties,
+// required, additionalProperties (as a bool), items, minimum, maximum,
+// minLength, maxLength, minItems and maxItems. Annotations such as title
+// and description are ignored, while any other keyword, e.g. $ref, oneOf
+// or pattern, panics naming it, rather than passing responses against
+// constraints that were never checked.
+func ResponseSchema(schema []byte, optFns ...ResponseSchemaOption) mux.Middleware {
+	var opts responseSchemaOpts
+	for _, opt := range optFns {
+		opt(&opts)
+	}
+
+	if opts.onMismatch == nil {
+		log := opts.log
+		if log == nil {
+			log = slog.Default()
+		}
+		opts.onMismatch = func(ctx context.Context, r *http.Request, err error) {
+			log.ErrorContext(ctx, "response schema mismatch", "method", r.Method, "path", r.URL.Path, "error", err)
+		}
+	}
+
+	s, err := compileSchema(schema)
+	if err != nil {
+		panic(err)
+	}
+
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			bw := &bufferedWriter{ResponseWriter: w, code: http.StatusOK}
+
+			err := handler(ctx, bw, r)
+
+			if bw.code >= 200 && bw.code < 300 && bw.buf.Len() > 0 && isJSON(bw.Header().Get("Content-Type")) {
+				if vErr := s.validateBytes(bw.buf.Bytes()); vErr != nil {
+					opts.onMismatch(ctx, r, vErr)
+
+					if opts.fail {
+						return errors.Join(err, errs.NewInternal(fmt.Errorf("response schema: %w", vErr)))
+					}
+				}
+			}
+
+			// A handler that wrote nothing, typically returning an error,
+			// leaves the response to the middleware wrapping this one.
+			if !bw.wroteHeader && bw.buf.Len() == 0 {
+				return err
+			}
+
+			w.WriteHeader(bw.code)
+			if _, wErr := w.Write(bw.buf.Bytes()); wErr != nil {
+				return errors.Join(err, fmt.Errorf("response schema: writing buffered body: %w", wErr))
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// bufferedWriter is an http.ResponseWriter, holding the status
+// code and body back so the response can be inspected before sending.
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+}
+
+func (bw *bufferedWriter) WriteHeader(code int) {
+	if bw.wroteHeader {
+		return
+	}
+
+	bw.code = code
+	bw.wroteHeader = true
+}
+
+func (bw *bufferedWriter) Write(p []byte) (int, error) {
+	if !bw.wroteHeader {
+		bw.WriteHeader(http.StatusOK)
+	}
+
+	return bw.buf.Write(p)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by
+// http.ResponseController.
+func (bw *bufferedWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// /////////////////////////////////////////////////////////////////////////////////////////////
+
+// schema is the supported subset of a JSON Schema document.
+type schema struct {
+	Type                 schemaTypes        `json:"type"`
+	Enum                 []any              `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+}
+
+// schemaTypes accepts the "type" keyword as either a string or an array of strings.
+type schemaTypes []string
+
+func (st *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*st = schemaTypes{single}
+		return nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return fmt.Errorf("type must be a string or array of strings: %w", err)
+	}
+	*st = multi
+
+	return nil
+}
+
+// schemaKeywords are the keywords of a schema that compileSchema accepts:
+// those validated, and annotations, which don't constrain a value.
+var schemaKeywords = map[string]bool{
+	"type":                 true,
+	"enum":                 true,
+	"properties":           true,
+	"required":             true,
+	"additionalProperties": true,
+	"items":                true,
+	"minimum":              true,
+	"maximum":              true,
+	"minLength":            true,
+	"maxLength":            true,
+	"minItems":             true,
+	"maxItems":             true,
+
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"deprecated":  true,
+}
+
+func compileSchema(data []byte) (*schema, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var s schema
+	if err := d.Decode(&s); err != nil {
+		return nil, fmt.Errorf("response schema: parsing schema: %w", err)
+	}
+
+	if unsupported := unsupportedKeywords("$", data); len(unsupported) > 0 {
+		return nil, fmt.Errorf("response schema: unsupported keywords: %s", strings.Join(unsupported, ", "))
+	}
+
+	return &s, nil
+}
+
+// unsupportedKeywords returns the sorted paths of the keywords in the
+// schema document data, or nested in its properties and items, that
+// aren't in schemaKeywords.
+func unsupportedKeywords(path string, data []byte) []string {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return nil // Not an object, which decoding the schema rejects.
+	}
+
+	var unsupported []string
+	for key, raw := range keywords {
+		switch {
+		case !schemaKeywords[key]:
+			unsupported = append(unsupported, path+"."+key)
+
+		case key == "items":
+			unsupported = append(unsupported, unsupportedKeywords(path+".items", raw)...)
+
+		case key == "properties":
+			var props map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &props); err != nil {
+				continue
+			}
+			for name, prop := range props {
+				unsupported = append(unsupported, unsupportedKeywords(path+".properties."+name, prop)...)
+			}
+		}
+	}
+	slices.Sort(unsupported)
+
+	return unsupported
+}
+
+func (s *schema) validateBytes(data []byte) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return s.validate("$", v)
+}
+
+// validate checks v against the schema, joining every violation found
+// beneath path.
+func (s *schema) validate(path string, v any) error {
+	if s == nil {
+		return nil
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return matchesType(t, v) }) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), typeOf(v))
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		return fmt.Errorf("%s: value %v not in enum", path, v)
+	}
+
+	var errs []error
+
+	switch val := v.(type) {
+	case map[string]any:
+		for _, key := range s.Required {
+			if _, ok := val[key]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing required property %q", path, key))
+			}
+		}
+		for key, child := range val {
+			prop, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, fmt.Errorf("%s: unexpected property %q", path, key))
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+key, child); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			errs = append(errs, fmt.Errorf("%s: expected at least %d items, got %d", path, *s.MinItems, len(val)))
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			errs = append(errs, fmt.Errorf("%s: expected at most %d items, got %d", path, *s.MaxItems, len(val)))
+		}
+		for i, child := range val {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), child); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.MinLength != nil && n < *s.MinLength {
+			errs = append(errs, fmt.Errorf("%s: expected length >= %d, got %d", path, *s.MinLength, n))
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs = append(errs, fmt.Errorf("%s: expected length <= %d, got %d", path, *s.MaxLength, n))
+		}
+
+	case json.Number:
+		f, err := val.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %s: %w", path, val, err)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			errs = append(errs, fmt.Errorf("%s: expected >= %v, got %s", path, *s.Minimum, val))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			errs = append(errs, fmt.Errorf("%s: expected <= %v, got %s", path, *s.Maximum, val))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func matchesType(t string, v any) bool {
+	switch t {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		if _, err := n.Int64(); err == nil {
+			return true
+		}
+		f, err := n.Float64()
+		return err == nil && f == float64(int64(f))
+	default:
+		return typeOf(v) == t || (t == "number" && typeOf(v) == "integer")
+	}
+}
+
+func typeOf(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if _, err := val.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,230 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["id", "name"],
+	"additionalProperties": false,
+	"properties": {
+		"id":   {"type": "integer", "minimum": 1},
+		"name": {"type": "string", "minLength": 1},
+		"role": {"type": "string", "enum": ["admin", "member"]},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+	}
+}`
+
+func TestResponseSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		body     any
+		wantErrs []string
+	}{
+		{"valid", http.StatusOK, map[string]any{"id": 1, "name": "alice", "role": "admin"}, nil},
+		{"missingRequired", http.StatusOK, map[string]any{"id": 1}, []string{`missing required property "name"`}},
+		{"wrongType", http.StatusOK, map[string]any{"id": "1", "name": "alice"}, []string{"$.id: expected integer, got string"}},
+		{"notInteger", http.StatusOK, map[string]any{"id": 1.5, "name": "alice"}, []string{"$.id: expected integer, got number"}},
+		{"belowMinimum", http.StatusOK, map[string]any{"id": 0, "name": "alice"}, []string{"$.id: expected >= 1"}},
+		{"notInEnum", http.StatusOK, map[string]any{"id": 1, "name": "alice", "role": "owner"}, []string{"$.role: value owner not in enum"}},
+		{"unexpectedProperty", http.StatusOK, map[string]any{"id": 1, "name": "alice", "extra": true}, []string{`unexpected property "extra"`}},
+		{"badItem", http.StatusOK, map[string]any{"id": 1, "name": "alice", "tags": []any{"a", 2}}, []string{"$.tags[1]: expected string, got integer"}},
+		{"tooManyItems", http.StatusOK, map[string]any{"id": 1, "name": "alice", "tags": []string{"a", "b", "c"}}, []string{"expected at most 2 items"}},
+		{"multipleErrors", http.StatusOK, map[string]any{"name": ""}, []string{`missing required property "id"`, "expected length >= 1"}},
+		{"errorStatusSkipped", http.StatusBadRequest, map[string]any{"code": 400}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotErr error
+			mw := middleware.ResponseSchema([]byte(userSchema), middleware.WithSchemaMismatchFunc(func(ctx context.Context, r *http.Request, err error) {
+				gotErr = err
+			}))
+
+			handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.RespondJSON(ctx, w, tc.code, tc.body)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			if err := handler(r.Context(), w, r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tc.code {
+				t.Errorf("status = %d, want %d", w.Code, tc.code)
+			}
+			if w.Body.Len() == 0 {
+				t.Error("expected original body to be sent")
+			}
+
+			if len(tc.wantErrs) == 0 {
+				if gotErr != nil {
+					t.Errorf("expected no mismatch, got: %v", gotErr)
+				}
+				return
+			}
+
+			if gotErr == nil {
+				t.Fatal("expected mismatch, got nil")
+			}
+			for _, want := range tc.wantErrs {
+				if !strings.Contains(gotErr.Error(), want) {
+					t.Errorf("expected mismatch to contain %q, got: %v", want, gotErr)
+				}
+			}
+		})
+	}
+}
+
+func TestResponseSchema_NonJSONSkipped(t *testing.T) {
+	mw := middleware.ResponseSchema([]byte(userSchema), middleware.WithSchemaMismatchFunc(func(ctx context.Context, r *http.Request, err error) {
+		t.Errorf("unexpected mismatch: %v", err)
+	}))
+
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/plain")
+		_, err := w.Write([]byte("hello"))
+		return err
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Body.String() != "hello" {
+		t.Errorf("body = %q, want %q", w.Body.String(), "hello")
+	}
+}
+
+func TestResponseSchema_HandlerError(t *testing.T) {
+	log, _ := newTestLogger(t)
+
+	app := mux.New(mux.WithMiddleware(middleware.Errors(log)))
+	app.Get("/users", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errs.New(http.StatusBadRequest, errors.New("bad filter"))
+	}, middleware.ResponseSchema([]byte(userSchema)))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), "bad filter") {
+		t.Errorf("body = %q, want the error response", w.Body.String())
+	}
+}
+
+func TestResponseSchema_Modes(t *testing.T) {
+	invalid := map[string]any{"id": 1}
+
+	tests := []struct {
+		name     string
+		fail     bool
+		wantCode int
+		wantBody string
+	}{
+		{name: "log", wantCode: http.StatusOK, wantBody: `{"id":1}`},
+		{name: "fail", fail: true, wantCode: http.StatusInternalServerError, wantBody: "Internal Server Error"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			log, buf := newTestLogger(t)
+
+			opts := []middleware.ResponseSchemaOption{middleware.WithSchemaLogger(log)}
+			if tc.fail {
+				opts = append(opts, middleware.WithSchemaFailOnMismatch())
+			}
+
+			app := mux.New(mux.WithMiddleware(middleware.Errors(log)))
+			app.Get("/users", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.RespondJSON(ctx, w, http.StatusOK, invalid)
+			}, middleware.ResponseSchema([]byte(userSchema), opts...))
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+			if w.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tc.wantCode)
+			}
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", w.Body.String(), tc.wantBody)
+			}
+			if !strings.Contains(buf.String(), `msg="response schema mismatch"`) || !strings.Contains(buf.String(), "missing required property") {
+				t.Errorf("expected mismatch logged, got: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestResponseSchema_InvalidSchema(t *testing.T) {
+	tests := map[string]struct {
+		schema    string
+		wantPanic string
+	}{
+		"badType":   {schema: `{"type": 1}`, wantPanic: "parsing schema"},
+		"ref":       {schema: `{"$ref": "#/definitions/user"}`, wantPanic: "unsupported keywords: $.$ref"},
+		"combiners": {schema: `{"oneOf": [{"type": "string"}], "allOf": []}`, wantPanic: "unsupported keywords: $.allOf, $.oneOf"},
+		"nested": {
+			schema:    `{"type": "object", "properties": {"email": {"type": "string", "format": "email"}, "tags": {"items": {"pattern": "^a"}}}}`,
+			wantPanic: "unsupported keywords: $.properties.email.format, $.properties.tags.items.pattern",
+		},
+		"schemaAdditionalProperties": {schema: `{"additionalProperties": {"type": "string"}}`, wantPanic: "parsing schema"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				err, _ := recover().(error)
+				if err == nil || !strings.Contains(err.Error(), tc.wantPanic) {
+					t.Errorf("panic = %v, want one containing %q", err, tc.wantPanic)
+				}
+			}()
+
+			middleware.ResponseSchema([]byte(tc.schema))
+		})
+	}
+}
+
+func TestResponseSchema_Annotations(t *testing.T) {
+	schema := `{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "User", "type": "object",
+		"properties": {"id": {"type": "integer", "description": "The user's ID", "examples": [1]}}}`
+
+	// Should not panic.
+	middleware.ResponseSchema([]byte(schema))
+}
+
+func TestResponseSchema_ResponseController(t *testing.T) {
+	mw := middleware.ResponseSchema([]byte(userSchema))
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return http.NewResponseController(w).Flush()
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("expected the flush to reach the recorder, got %v", err)
+	}
+	if !w.Flushed {
+		t.Error("expected the recorder to be flushed")
+	}
+}
@@ -0,0 +1,93 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []middleware.SecureHeadersOption
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "strict-origin-when-cross-origin",
+				"Strict-Transport-Security": "",
+			},
+		},
+		{
+			name: "hsts",
+			opts: []middleware.SecureHeadersOption{middleware.WithHSTS(365*24*time.Hour, true)},
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+			},
+		},
+		{
+			name: "hsts without subdomains",
+			opts: []middleware.SecureHeadersOption{middleware.WithHSTS(time.Hour, false)},
+			want: map[string]string{"Strict-Transport-Security": "max-age=3600"},
+		},
+		{
+			name: "invalid hsts ignored",
+			opts: []middleware.SecureHeadersOption{middleware.WithHSTS(0, true)},
+			want: map[string]string{"Strict-Transport-Security": ""},
+		},
+		{
+			name: "overridden policies",
+			opts: []middleware.SecureHeadersOption{middleware.WithFrameOptions("SAMEORIGIN"), middleware.WithReferrerPolicy("no-referrer")},
+			want: map[string]string{"X-Frame-Options": "SAMEORIGIN", "Referrer-Policy": "no-referrer"},
+		},
+		{
+			name: "omitted policies",
+			opts: []middleware.SecureHeadersOption{middleware.WithFrameOptions(""), middleware.WithReferrerPolicy("")},
+			want: map[string]string{"X-Content-Type-Options": "nosniff", "X-Frame-Options": "", "Referrer-Policy": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := middleware.SecureHeaders(tt.opts...)(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusOK)
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if err := handler(r.Context(), w, r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for key, want := range tt.want {
+				if got := w.Header().Get(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSecureHeaders_Unrouted(t *testing.T) {
+	app := mux.New(mux.WithMiddleware(middleware.SecureHeaders()))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// SlowRequest logs a warning tagged slow=true for each request whose
+// handler takes longer than threshold, measured from the request's
+// [mux.BaseValues] Now. The line carries the trace ID, method, matched
+// route pattern, path and duration, so slow requests can be filtered
+// without percentile queries. It's in addition to the completion line of
+// [Logger], and requests within the threshold aren't logged.
+func SlowRequest(threshold time.Duration, log *slog.Logger) mux.Middleware {
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := handler(ctx, w, r)
+
+			v := mux.GetValues(ctx)
+			if since := time.Since(v.Now); since > threshold {
+				route := r.Pattern
+				if route == "" {
+					route = r.URL.Path
+				}
+
+				log.Warn("slow request", "trace_id", v.TraceID, "slow", true, "method", r.Method, "route", route,
+					"path", r.URL.Path, "since", since.String(), "threshold", threshold.String())
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestSlowRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		now      time.Time
+		err      error
+		wantSlow bool
+	}{
+		{name: "fast", now: time.Now(), wantSlow: false},
+		{name: "slow", now: time.Now().Add(-2 * time.Second), wantSlow: true},
+		{name: "slow with error", now: time.Now().Add(-2 * time.Second), err: errors.New("boom"), wantSlow: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			log, buf := newTestLogger(t)
+
+			mw := middleware.SlowRequest(time.Second, log)
+			handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusOK)
+				return tc.err
+			})
+
+			ctx := mux.NewTestContext(mux.WithTestNow(tc.now), mux.WithTestTraceID("trace-1"))
+			r := httptest.NewRequest(http.MethodGet, "/items/42", nil).WithContext(ctx)
+			r.Pattern = "GET /items/{id}"
+
+			if err := handler(ctx, httptest.NewRecorder(), r); !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+
+			output := buf.String()
+			if !tc.wantSlow {
+				if output != "" {
+					t.Fatalf("expected no log for a fast request, got: %s", output)
+				}
+				return
+			}
+
+			for _, want := range []string{"level=WARN", "slow=true", `route="GET /items/{id}"`, "path=/items/42", "trace_id=trace-1", "threshold=1s"} {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected %q in log output: %s", want, output)
+				}
+			}
+		})
+	}
+}
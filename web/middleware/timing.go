@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// ServerTiming places a [web.TimingRecorder] in the request context and
+// serializes its measurements into the Server-Timing response header.
+// The header is written when the response headers are sent, so phases
+// must be stopped before the handler writes its response.
+func ServerTiming() mux.Middleware {
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx, rec := web.WithTiming(ctx)
+
+			tw := &timingWriter{ResponseWriter: w, rec: rec}
+
+			return handler(ctx, tw, r.WithContext(ctx))
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// timingWriter is an http.ResponseWriter, adding the
+// Server-Timing header just before the headers are sent.
+type timingWriter struct {
+	http.ResponseWriter
+	rec         *web.TimingRecorder
+	wroteHeader bool
+}
+
+func (tw *timingWriter) WriteHeader(code int) {
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		if v := tw.rec.Header(); v != "" {
+			tw.Header().Set("Server-Timing", v)
+		}
+	}
+
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timingWriter) Write(p []byte) (int, error) {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+
+	return tw.ResponseWriter.Write(p)
+}
+
+// Flush sends the headers, with the metrics recorded so far, if not yet
+// sent, then flushes the response to the client.
+func (tw *timingWriter) Flush() {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+
+	_ = http.NewResponseController(tw.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by
+// http.ResponseController.
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
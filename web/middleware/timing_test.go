@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/middleware"
+)
+
+func TestServerTiming(t *testing.T) {
+	mw := middleware.ServerTiming()
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if web.Timing(ctx) != web.Timing(r.Context()) {
+			t.Error("expected recorder in both ctx and request context")
+		}
+
+		_ = web.Timing(ctx).Measure("db", func() error { return nil })
+
+		return web.RespondJSON(ctx, w, http.StatusOK, map[string]string{"ok": "true"})
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := w.Header().Get("Server-Timing"); !strings.HasPrefix(got, "db;dur=") {
+		t.Errorf("expected Server-Timing header with db metric, got %q", got)
+	}
+}
+
+func TestServerTiming_NoMetrics(t *testing.T) {
+	mw := middleware.ServerTiming()
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := w.Write([]byte("ok"))
+		return err
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := w.Header()["Server-Timing"]; ok {
+		t.Error("expected no Server-Timing header")
+	}
+}
+
+func TestServerTiming_Flush(t *testing.T) {
+	mw := middleware.ServerTiming()
+	handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_ = web.Timing(ctx).Measure("setup", func() error { return nil })
+
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			return err
+		}
+
+		_, err := w.Write([]byte("data: hi\n\n"))
+		return err
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+
+	if err := handler(r.Context(), w, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if got := w.Header().Get("Server-Timing"); !strings.HasPrefix(got, "setup;dur=") {
+		t.Errorf("expected Server-Timing header sent with the flush, got %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+// RequireTLSOption is a functional option for [RequireTLS].
+type RequireTLSOption func(*requireTLSOpts)
+
+type requireTLSOpts struct {
+	redirect bool
+}
+
+// WithTLSRedirect redirects plaintext GET and HEAD requests to the same
+// URL over https with 301 Moved Permanently, instead of rejecting them.
+// Other methods are still rejected, as their body was already sent in
+// plaintext.
+func WithTLSRedirect() RequireTLSOption {
+	return func(opts *requireTLSOpts) {
+		opts.redirect = true
+	}
+}
+
+// RequireTLS rejects plaintext requests with 403 Forbidden, guarding
+// against a plaintext listener being exposed by mistake. A request is
+// secure if it arrived over TLS, or for an App built with
+// [mux.WithTrustForwardedHeaders], if the proxy in front of it says so
+// with an X-Forwarded-Proto of https.
+func RequireTLS(optFns ...RequireTLSOption) mux.Middleware {
+	var opts requireTLSOpts
+	for _, opt := range optFns {
+		opt(&opts)
+	}
+
+	m := func(handler mux.Handler) mux.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.TLS != nil {
+				return handler(ctx, w, r)
+			}
+
+			if mux.TrustsForwardedHeaders(ctx) {
+				proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+				if strings.EqualFold(strings.TrimSpace(proto), "https") {
+					return handler(ctx, w, r)
+				}
+			}
+
+			if opts.redirect && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+				// The host the client used, forwarded by a trusted proxy or not.
+				target, err := url.Parse(web.AbsoluteURL(r, r.URL.RequestURI()))
+				if err != nil {
+					return web.RespondError(ctx, w, errs.New(http.StatusBadRequest, err))
+				}
+				target.Scheme = "https"
+
+				return web.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+			}
+
+			return web.RespondError(ctx, w, errs.New(http.StatusForbidden, errors.New("TLS required")))
+		}
+
+		return h
+	}
+
+	return m
+}
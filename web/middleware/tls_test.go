@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestRequireTLS(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		tls          bool
+		proto        string
+		trust        bool
+		host         string
+		opts         []middleware.RequireTLSOption
+		wantCode     int
+		wantLocation string
+	}{
+		{name: "tls", method: http.MethodGet, target: "/a", tls: true, wantCode: http.StatusOK},
+		{name: "plaintext", method: http.MethodGet, target: "/a", wantCode: http.StatusForbidden},
+		{name: "untrusted proxy header", method: http.MethodGet, target: "/a", proto: "https", wantCode: http.StatusForbidden},
+		{name: "trusted proxy header", method: http.MethodGet, target: "/a", proto: "https", trust: true, wantCode: http.StatusOK},
+		{name: "trusted proxy header list", method: http.MethodGet, target: "/a", proto: "HTTPS, http", trust: true, wantCode: http.StatusOK},
+		{name: "trusted proxy plaintext", method: http.MethodGet, target: "/a", proto: "http", trust: true, wantCode: http.StatusForbidden},
+		{name: "redirect get", method: http.MethodGet, target: "/a/b?x=1", opts: []middleware.RequireTLSOption{middleware.WithTLSRedirect()}, wantCode: http.StatusMovedPermanently, wantLocation: "https://example.com/a/b?x=1"},
+		{name: "redirect forwarded host", method: http.MethodGet, target: "/a", proto: "http", trust: true, host: "public.example", opts: []middleware.RequireTLSOption{middleware.WithTLSRedirect()}, wantCode: http.StatusMovedPermanently, wantLocation: "https://public.example/a"},
+		{name: "redirect spoofed host", method: http.MethodGet, target: "/a", proto: "http", host: "evil.example", opts: []middleware.RequireTLSOption{middleware.WithTLSRedirect()}, wantCode: http.StatusMovedPermanently, wantLocation: "https://example.com/a"},
+		{name: "redirect rejects post", method: http.MethodPost, target: "/a", opts: []middleware.RequireTLSOption{middleware.WithTLSRedirect()}, wantCode: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := middleware.RequireTLS(tt.opts...)
+			handler := mw(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusOK)
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if tt.host != "" {
+				r.Header.Set("X-Forwarded-Host", tt.host)
+			}
+			if tt.trust {
+				r = r.WithContext(mux.NewTestContext(mux.WithTestTrustForwardedHeaders()))
+			}
+
+			if err := handler(r.Context(), w, r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected Location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ type ctxKey int
 
 const (
 	base ctxKey = iota + 1
+	trustForwarded
 )
 
 const emptyUUID = "00000000-0000-0000-0000-000000000000"
@@ -23,7 +24,11 @@ type BaseValues struct {
 	TraceID    string
 	Now        time.Time
 	Tracer     trace.Tracer
+	RequestID  string
 	StatusCode int
+	Err        error
+
+	metrics *counters
 }
 
 // SetStatusCode updates the BaseValue's status code.
@@ -36,6 +41,41 @@ func SetStatusCode(ctx context.Context, statusCode int) {
 	v.StatusCode = statusCode
 }
 
+// SetRequestID records the request's ID on the BaseValues, as done by
+// the RequestID middleware.
+func SetRequestID(ctx context.Context, requestID string) {
+	v, ok := ctx.Value(base).(*BaseValues)
+	if !ok {
+		return
+	}
+
+	v.RequestID = requestID
+}
+
+// SetError records the error a handler returned on the BaseValues, so
+// middleware further out, such as the logger, can report it after an
+// inner middleware has handled it.
+func SetError(ctx context.Context, err error) {
+	v, ok := ctx.Value(base).(*BaseValues)
+	if !ok {
+		return
+	}
+
+	v.Err = err
+}
+
+// RecordPanic counts a recovered panic towards the App's [AppMetrics].
+// It's called by the Panics middleware, and is a no-op outside a request
+// handled by an App.
+func RecordPanic(ctx context.Context) {
+	v, ok := ctx.Value(base).(*BaseValues)
+	if !ok || v.metrics == nil {
+		return
+	}
+
+	v.metrics.panics.Add(1)
+}
+
 // GetValues retrieves the BaseValues from the given context.
 func GetValues(ctx context.Context) *BaseValues {
 	v, ok := ctx.Value(base).(*BaseValues)
@@ -50,6 +90,14 @@ func GetValues(ctx context.Context) *BaseValues {
 	return v
 }
 
+// TrustsForwardedHeaders reports whether the request is served by an App
+// built with [WithTrustForwardedHeaders], so its X-Forwarded-Proto and
+// X-Forwarded-Host were set by a proxy rather than the client.
+func TrustsForwardedHeaders(ctx context.Context) bool {
+	trusted, _ := ctx.Value(trustForwarded).(bool)
+	return trusted
+}
+
 // GetTraceID retrieves the current trace ID from the BaseValue in the given context.
 // We return an empty uuid for testing purposes if not set.
 func GetTraceID(ctx context.Context) string {
@@ -61,6 +109,22 @@ func GetTraceID(ctx context.Context) string {
 	return v.TraceID
 }
 
+// GetRequestID retrieves the request ID set by the RequestID middleware,
+// falling back to the trace ID when none was set, so there's always an ID
+// to correlate by. It's empty outside a request handled by an App.
+func GetRequestID(ctx context.Context) string {
+	v, ok := ctx.Value(base).(*BaseValues)
+	if !ok {
+		return ""
+	}
+
+	if v.RequestID != "" {
+		return v.RequestID
+	}
+
+	return v.TraceID
+}
+
 // AddSpan adds a span to the tracer, returning it and the context.
 func AddSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyValue) (context.Context, trace.Span) {
 	v, ok := ctx.Value(base).(*BaseValues)
@@ -74,6 +138,97 @@ func AddSpan(ctx context.Context, spanName string, keyValues ...attribute.KeyVal
 	return ctx, span
 }
 
+// TestContextOption configures the context built by [NewTestContext].
+type TestContextOption func(*testContext)
+
+type testContext struct {
+	parent         context.Context
+	values         BaseValues
+	extra          []any
+	trustForwarded bool
+}
+
+// WithTestParent derives the test context from parent, e.g. t.Context()
+// or a context carrying a deadline. Default is context.Background().
+func WithTestParent(parent context.Context) TestContextOption {
+	return func(tc *testContext) {
+		tc.parent = parent
+	}
+}
+
+// WithTestTraceID sets the trace ID. Default is a random UUID.
+func WithTestTraceID(traceID string) TestContextOption {
+	return func(tc *testContext) {
+		tc.values.TraceID = traceID
+	}
+}
+
+// WithTestRequestID sets the request ID returned by [GetRequestID].
+// Default is empty, falling back to the trace ID.
+func WithTestRequestID(requestID string) TestContextOption {
+	return func(tc *testContext) {
+		tc.values.RequestID = requestID
+	}
+}
+
+// WithTestNow sets the request start time. Default is time.Now().UTC().
+func WithTestNow(now time.Time) TestContextOption {
+	return func(tc *testContext) {
+		tc.values.Now = now
+	}
+}
+
+// WithTestTracer sets the tracer used by [AddSpan]. Default is a no-op tracer.
+func WithTestTracer(tracer trace.Tracer) TestContextOption {
+	return func(tc *testContext) {
+		tc.values.Tracer = tracer
+	}
+}
+
+// WithTestTrustForwardedHeaders marks the context as trusting forwarded
+// headers, as [WithTrustForwardedHeaders] does. Default is untrusted.
+func WithTestTrustForwardedHeaders() TestContextOption {
+	return func(tc *testContext) {
+		tc.trustForwarded = true
+	}
+}
+
+// WithTestValue stores an arbitrary key/value pair in the context, for
+// values normally set by application middleware such as an authenticated
+// principal or request ID.
+func WithTestValue(key, val any) TestContextOption {
+	return func(tc *testContext) {
+		tc.extra = append(tc.extra, key, val)
+	}
+}
+
+// NewTestContext builds a context populated with [BaseValues] as the App
+// would set them for a matched route, so a [Handler] can be unit tested
+// by calling it directly, without the App or its middleware chain.
+func NewTestContext(opts ...TestContextOption) context.Context {
+	tc := testContext{
+		parent: context.Background(),
+		values: BaseValues{
+			TraceID: uuid.New().String(),
+			Now:     time.Now().UTC(),
+			Tracer:  noop.NewTracerProvider().Tracer("no-op tracer"),
+		},
+	}
+	for _, opt := range opts {
+		opt(&tc)
+	}
+
+	ctx := setValues(tc.parent, &tc.values)
+	if tc.trustForwarded {
+		ctx = context.WithValue(ctx, trustForwarded, true)
+	}
+	for i := 0; i < len(tc.extra); i += 2 {
+		ctx = context.WithValue(ctx, tc.extra[i], tc.extra[i+1])
+	}
+
+	return ctx
+}
+
 // setValues sets the specified BaseValues in the context.
 func setValues(ctx context.Context, v *BaseValues) context.Context {
 	return context.WithValue(ctx, base, v)
@@ -2,7 +2,10 @@ package mux_test
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -46,3 +49,101 @@ func TestAddSpan_NoValues(t *testing.T) {
 		t.Fatal("span should not be nil")
 	}
 }
+
+func TestNewTestContext_Defaults(t *testing.T) {
+	ctx := mux.NewTestContext()
+
+	v := mux.GetValues(ctx)
+	if _, err := uuid.Parse(v.TraceID); err != nil || v.TraceID == uuid.Nil.String() {
+		t.Fatalf("TraceID = %q, want a random UUID", v.TraceID)
+	}
+	if v.Tracer == nil {
+		t.Fatal("Tracer should be non-nil (noop)")
+	}
+	if v.Now.IsZero() {
+		t.Fatal("Now should be non-zero")
+	}
+
+	mux.SetStatusCode(ctx, http.StatusTeapot)
+	if v.StatusCode != http.StatusTeapot {
+		t.Fatalf("StatusCode = %d, want %d", v.StatusCode, http.StatusTeapot)
+	}
+}
+
+func TestNewTestContext_WithOptions(t *testing.T) {
+	type principalKey struct{}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	deadline := time.Now().Add(time.Minute)
+	parent, cancel := context.WithDeadline(t.Context(), deadline)
+	defer cancel()
+
+	ctx := mux.NewTestContext(
+		mux.WithTestParent(parent),
+		mux.WithTestTraceID("trace-123"),
+		mux.WithTestNow(now),
+		mux.WithTestValue(principalKey{}, "alice"),
+	)
+
+	if got := mux.GetTraceID(ctx); got != "trace-123" {
+		t.Errorf("TraceID = %q, want %q", got, "trace-123")
+	}
+	if got := mux.GetValues(ctx).Now; !got.Equal(now) {
+		t.Errorf("Now = %v, want %v", got, now)
+	}
+	if got, _ := ctx.Value(principalKey{}).(string); got != "alice" {
+		t.Errorf("principal = %q, want %q", got, "alice")
+	}
+	if got, ok := ctx.Deadline(); !ok || !got.Equal(deadline) {
+		t.Errorf("deadline = %v, want %v", got, deadline)
+	}
+}
+
+func TestSetError(t *testing.T) {
+	ctx := mux.NewTestContext()
+	err := errors.New("boom")
+
+	mux.SetError(ctx, err)
+
+	if got := mux.GetValues(ctx).Err; got != err {
+		t.Fatalf("Err = %v, want %v", got, err)
+	}
+}
+
+func TestSetError_NoValues(t *testing.T) {
+	// Should not panic.
+	mux.SetError(context.Background(), errors.New("boom"))
+}
+
+func TestTrustsForwardedHeaders(t *testing.T) {
+	if mux.TrustsForwardedHeaders(context.Background()) {
+		t.Fatal("expected a bare context to not trust forwarded headers")
+	}
+	if mux.TrustsForwardedHeaders(mux.NewTestContext()) {
+		t.Fatal("expected the test context to not trust forwarded headers by default")
+	}
+	if !mux.TrustsForwardedHeaders(mux.NewTestContext(mux.WithTestTrustForwardedHeaders())) {
+		t.Fatal("expected WithTestTrustForwardedHeaders to trust forwarded headers")
+	}
+}
+
+func TestGetRequestID(t *testing.T) {
+	if id := mux.GetRequestID(context.Background()); id != "" {
+		t.Fatalf("GetRequestID without BaseValues = %q, want empty", id)
+	}
+
+	ctx := mux.NewTestContext(mux.WithTestTraceID("trace-1"))
+	if id := mux.GetRequestID(ctx); id != "trace-1" {
+		t.Fatalf("GetRequestID = %q, want the trace ID when unset", id)
+	}
+
+	mux.SetRequestID(ctx, "req-1")
+	if id := mux.GetRequestID(ctx); id != "req-1" {
+		t.Fatalf("GetRequestID = %q, want %q", id, "req-1")
+	}
+
+	ctx = mux.NewTestContext(mux.WithTestRequestID("req-2"))
+	if id := mux.GetRequestID(ctx); id != "req-2" {
+		t.Fatalf("GetRequestID = %q, want %q", id, "req-2")
+	}
+}
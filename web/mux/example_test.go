@@ -152,7 +152,7 @@ func ExampleApp_Use() {
 
 func ExampleWithMiddleware() {
 	// WithMiddleware auto-categorizes by function name:
-	// CORS → global, Logger/Errors → route-level, Panics → outermost route-level.
+	// CORS → global, Logger/Errors → route-level, Panics → innermost route-level.
 	app := mux.New(
 		mux.WithMiddleware(
 			middleware.CORS([]string{"*"}),
@@ -186,3 +186,54 @@ func ExampleWithStaticFS() {
 	// 200
 	// static content
 }
+
+func ExampleWithPprof() {
+	basicAuth := func(handler mux.Handler) mux.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return nil
+			}
+			return handler(ctx, w, r)
+		}
+	}
+
+	app := mux.New(mux.WithPprof("/debug/pprof", basicAuth))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil)
+	app.ServeHTTP(w, r)
+	fmt.Println(w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil)
+	r.SetBasicAuth("admin", "secret")
+	app.ServeHTTP(w, r)
+	fmt.Println(w.Code)
+	// Output:
+	// 401
+	// 200
+}
+
+
+func ExampleNewTestContext() {
+	type userKey struct{}
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		user, _ := ctx.Value(userKey{}).(string)
+		fmt.Fprintf(w, "%s %s", mux.GetTraceID(ctx), user)
+		return nil
+	}
+
+	ctx := mux.NewTestContext(
+		mux.WithTestTraceID("trace-1"),
+		mux.WithTestValue(userKey{}, "alice"),
+	)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/me", nil).WithContext(ctx)
+	handler(ctx, w, r)
+
+	fmt.Println(w.Body.String())
+	// Output: trace-1 alice
+}
@@ -0,0 +1,29 @@
+package mux
+
+import "sync/atomic"
+
+// AppMetrics is a snapshot of the request counters kept by an App.
+type AppMetrics struct {
+	Requests int64
+	Errors   int64
+	Panics   int64
+}
+
+// counters is shared by an App and every App derived from it via Group
+// and Mount, so Metrics reports the totals for the whole mux.
+type counters struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	panics   atomic.Int64
+}
+
+// Metrics returns the number of requests handled by routes registered
+// through Handle, how many of them returned or recorded an error, and how
+// many panicked, as reported by the Panics middleware.
+func (a *App) Metrics() AppMetrics {
+	return AppMetrics{
+		Requests: a.counters.requests.Load(),
+		Errors:   a.counters.errors.Load(),
+		Panics:   a.counters.panics.Load(),
+	}
+}
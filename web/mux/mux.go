@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"slices"
 	"strings"
@@ -26,6 +27,13 @@ type App struct {
 	group    string
 	logger   *slog.Logger
 	tracer   trace.Tracer
+	counters *counters
+	routes   *routeTable
+
+	notFound         Handler
+	methodNotAllowed Handler
+	autoOptions      bool
+	trustForwarded   bool
 }
 
 // Handler is a http.Handler that returns an error.
@@ -56,23 +64,42 @@ func New(optFns ...Option) *App {
 		mw:       opts.mw,
 		logger:   opts.logger,
 		tracer:   opts.tracer,
+		counters: &counters{},
+		routes:   &routeTable{},
+
+		notFound:         opts.notFound,
+		methodNotAllowed: opts.methodNotAllowed,
+		autoOptions:      opts.autoOptions,
+		trustForwarded:   opts.trustForwarded,
 	}
 
 	if opts.staticFS != nil {
 		app.HandleNoMiddleware(http.MethodGet, "", opts.staticPath, opts.staticFS)
 	}
 
+	if opts.pprofPath != "" {
+		app.registerPprof(opts.pprofPath, opts.pprofMW...)
+	}
+
 	return app
 }
 
 // ServeHTTP implements http.Handler, wrapping global middleware before serving the request.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	serveHTTP := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if a.notFound != nil || a.methodNotAllowed != nil || a.autoOptions {
+			w = &unroutedWriter{ResponseWriter: w, a: a, r: r}
+		}
+
 		a.mux.ServeHTTP(w, r)
 		return nil
 	}
 	wrapped := wrap(a.globalMW, serveHTTP)
 
+	if a.trustForwarded {
+		r = r.WithContext(context.WithValue(r.Context(), trustForwarded, true))
+	}
+
 	if err := wrapped(r.Context(), w, r); err != nil {
 		a.logger.Error("mux", "serve http", err)
 	}
@@ -87,6 +114,13 @@ func (a *App) Group() *App {
 		mw:       slices.Clone(a.mw),
 		logger:   a.logger,
 		tracer:   a.tracer,
+		counters: a.counters,
+		routes:   a.routes,
+
+		notFound:         a.notFound,
+		methodNotAllowed: a.methodNotAllowed,
+		autoOptions:      a.autoOptions,
+		trustForwarded:   a.trustForwarded,
 	}
 }
 
@@ -100,6 +134,13 @@ func (a *App) Mount(subRoute string) *App {
 		logger:   a.logger,
 		group:    strings.TrimLeft(subRoute, "/"),
 		tracer:   a.tracer,
+		counters: a.counters,
+		routes:   a.routes,
+
+		notFound:         a.notFound,
+		methodNotAllowed: a.methodNotAllowed,
+		autoOptions:      a.autoOptions,
+		trustForwarded:   a.trustForwarded,
 	}
 }
 
@@ -108,9 +149,13 @@ func (a *App) Use(mw ...Middleware) {
 	a.mw = append(a.mw, mw...)
 }
 
-// Get registers a handler for GET requests at the given path.
+// Get registers a handler for GET requests at the given path. With
+// [WithAutoOptions], the handler is registered for HEAD requests too.
 func (a *App) Get(path string, fn Handler, mw ...Middleware) {
 	a.Handle(http.MethodGet, a.group, path, fn, mw...)
+	if a.autoOptions {
+		a.Handle(http.MethodHead, a.group, path, fn, mw...)
+	}
 }
 
 // Post registers a handler for POST requests at the given path.
@@ -134,10 +179,18 @@ func (a *App) Delete(path string, fn Handler, mw ...Middleware) {
 }
 
 func (a *App) Handle(method, group, path string, handler Handler, mw ...Middleware) {
+	h := a.handlerFunc(handler, mw...)
+
+	a.register(method, group, path, h, len(mw) > 0)
+}
+
+// handlerFunc wraps handler in the route and App middleware, serving it
+// with a span, the request's [BaseValues] and the App's metrics.
+func (a *App) handlerFunc(handler Handler, mw ...Middleware) http.HandlerFunc {
 	handler = wrap(mw, handler)
 	handler = wrap(a.mw, handler)
 
-	h := func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, span := a.startSpan(w, r)
 		defer span.End()
 
@@ -150,23 +203,117 @@ func (a *App) Handle(method, group, path string, handler Handler, mw ...Middlewa
 			TraceID: traceID,
 			Now:     time.Now().UTC(),
 			Tracer:  a.tracer,
+			metrics: a.counters,
 		}
 
 		r = r.WithContext(setValues(ctx, &v))
 
-		if err := handler(r.Context(), w, r); err != nil {
+		a.counters.requests.Add(1)
+
+		err := handler(r.Context(), w, r)
+		if err != nil {
 			a.logger.Error("mux", "handle", err)
 		}
+		if err != nil || v.Err != nil {
+			a.counters.errors.Add(1)
+		}
 	}
+}
 
-	finalPath := path
-	if group != "" {
-		finalPath = fmt.Sprintf("/%s%s", group, path)
+// serveUnrouted answers r in place of the ServeMux's own response with
+// code, with the handlers of [WithNotFoundHandler] and
+// [WithMethodNotAllowedHandler] or the OPTIONS response of
+// [WithAutoOptions], reporting whether it did. allow is the Allow header
+// the ServeMux computed for a 405.
+func (a *App) serveUnrouted(w http.ResponseWriter, r *http.Request, code int, allow string) bool {
+	switch {
+	case code == http.StatusMethodNotAllowed && r.Method == http.MethodOptions && a.autoOptions:
+		w.Header().Set("Allow", allow+", "+http.MethodOptions)
+		w.WriteHeader(http.StatusNoContent)
+	case code == http.StatusNotFound && a.notFound != nil:
+		a.handlerFunc(a.notFound)(w, r)
+	case code == http.StatusMethodNotAllowed && a.methodNotAllowed != nil:
+		w.Header().Set("Allow", allow)
+		a.handlerFunc(a.methodNotAllowed)(w, r)
+	default:
+		return false
 	}
 
-	pattern := fmt.Sprintf("%s %s", method, finalPath)
+	return true
+}
 
-	a.mux.HandleFunc(pattern, h)
+// unroutedWriter is an http.ResponseWriter, passing through the response
+// of a request the ServeMux routed, while holding back its own response
+// to one it couldn't, which leaves r.Pattern empty, so [App.serveUnrouted]
+// can replace it. The ServeMux looks r up once.
+type unroutedWriter struct {
+	http.ResponseWriter
+	a           *App
+	r           *http.Request
+	header      http.Header
+	wroteHeader bool
+	replaced    bool
+}
+
+func (uw *unroutedWriter) routed() bool {
+	return uw.r.Pattern != ""
+}
+
+func (uw *unroutedWriter) Header() http.Header {
+	if uw.routed() {
+		return uw.ResponseWriter.Header()
+	}
+
+	if uw.header == nil {
+		uw.header = make(http.Header)
+	}
+
+	return uw.header
+}
+
+func (uw *unroutedWriter) WriteHeader(code int) {
+	if uw.routed() {
+		uw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if uw.wroteHeader {
+		return
+	}
+	uw.wroteHeader = true
+
+	if uw.a.serveUnrouted(uw.ResponseWriter, uw.r, code, uw.Header().Get("Allow")) {
+		uw.replaced = true
+		return
+	}
+
+	maps.Copy(uw.ResponseWriter.Header(), uw.header)
+	uw.ResponseWriter.WriteHeader(code)
+}
+
+func (uw *unroutedWriter) Write(p []byte) (int, error) {
+	if !uw.routed() {
+		if !uw.wroteHeader {
+			uw.WriteHeader(http.StatusOK)
+		}
+		if uw.replaced {
+			return len(p), nil
+		}
+	}
+
+	return uw.ResponseWriter.Write(p)
+}
+
+// Flush flushes the response of a routed request to the client.
+func (uw *unroutedWriter) Flush() {
+	if uw.routed() {
+		_ = http.NewResponseController(uw.ResponseWriter).Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for use by
+// http.ResponseController.
+func (uw *unroutedWriter) Unwrap() http.ResponseWriter {
+	return uw.ResponseWriter
 }
 
 func (a *App) HandleRaw(method, group, path string, handler http.Handler, mw ...Middleware) {
@@ -182,6 +329,12 @@ func (a *App) HandleNoMiddleware(method, group, path string, handler Handler) {
 		}
 	}
 
+	a.register(method, group, path, h, false)
+}
+
+// register adds h to the ServeMux under method and the group-prefixed
+// path, recording the route for [App.Routes].
+func (a *App) register(method, group, path string, h http.HandlerFunc, hasMW bool) {
 	finalPath := path
 	if group != "" {
 		finalPath = fmt.Sprintf("/%s%s", group, path)
@@ -190,12 +343,23 @@ func (a *App) HandleNoMiddleware(method, group, path string, handler Handler) {
 	pattern := fmt.Sprintf("%s %s", method, finalPath)
 
 	a.mux.HandleFunc(pattern, h)
+	a.routes.add(RouteInfo{Method: method, Pattern: finalPath, Middleware: hasMW})
 }
 
 // startSpan initializes the request by adding a span and writing
 // otel-related info into the response writer for the response.
-func (a *App) startSpan(w http.ResponseWriter, r *http.Request) (context.Context, trace.Span) {
-	ctx, span := a.tracer.Start(r.Context(), "mux.handler")
+// A panicking tracer or propagator is recovered and logged, so a
+// tracing failure degrades to an untraced request instead of killing it.
+func (a *App) startSpan(w http.ResponseWriter, r *http.Request) (ctx context.Context, span trace.Span) {
+	ctx, span = r.Context(), trace.SpanFromContext(r.Context())
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			a.logger.Error("mux", "start span", fmt.Errorf("recovered tracing panic: %v", rec))
+		}
+	}()
+
+	ctx, span = a.tracer.Start(r.Context(), "mux.handler")
 	span.SetAttributes(attribute.String("path", r.RequestURI))
 
 	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(w.Header()))
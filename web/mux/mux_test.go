@@ -10,9 +10,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
 	"github.com/adamwoolhether/httper/web"
 	"github.com/adamwoolhether/httper/web/errs"
 	"github.com/adamwoolhether/httper/web/middleware"
@@ -619,3 +625,157 @@ func newTestLogger(t *testing.T) (*slog.Logger, *bytes.Buffer) {
 	})
 	return log, &buf
 }
+
+func TestApp_TracingPanicRecovered(t *testing.T) {
+	tests := map[string]struct {
+		opts  []mux.Option
+		setup func(t *testing.T)
+	}{
+		"tracer": {
+			opts: []mux.Option{mux.WithTracer(panicTracer{})},
+		},
+		"propagator": {
+			setup: func(t *testing.T) {
+				prev := otel.GetTextMapPropagator()
+				otel.SetTextMapPropagator(panicPropagator{})
+				t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+
+			var buf bytes.Buffer
+			log := slog.New(slog.NewTextHandler(&buf, nil))
+
+			app := mux.New(append(tt.opts, mux.WithLogger(log))...)
+			app.Get("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				if mux.GetTraceID(ctx) == "" {
+					t.Error("expected a trace ID")
+				}
+				w.WriteHeader(http.StatusOK)
+				return nil
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/ping", nil)
+			app.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if !strings.Contains(buf.String(), "recovered tracing panic") {
+				t.Errorf("expected tracing panic to be logged: %s", buf.String())
+			}
+		})
+	}
+}
+
+// panicTracer is a trace.Tracer whose Start always panics.
+type panicTracer struct {
+	noop.Tracer
+}
+
+func (panicTracer) Start(context.Context, string, ...trace.SpanStartOption) (context.Context, trace.Span) {
+	panic("tracer misconfigured")
+}
+
+// panicPropagator is a propagation.TextMapPropagator whose Inject always panics.
+type panicPropagator struct {
+	propagation.TraceContext
+}
+
+func (panicPropagator) Inject(context.Context, propagation.TextMapCarrier) {
+	panic("propagator misconfigured")
+}
+
+func TestApp_Metrics(t *testing.T) {
+	app, srv, _ := newFullStackApp(t)
+
+	app.Get("/ok", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+	app.Get("/err", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errs.New(http.StatusBadRequest, fmt.Errorf("bad input"))
+	})
+	app.Mount("/v1").Get("/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("boom")
+	})
+
+	for _, path := range []string{"/ok", "/ok", "/err", "/v1/panic"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	got := app.Metrics()
+	want := mux.AppMetrics{Requests: 4, Errors: 2, Panics: 1}
+	if got != want {
+		t.Fatalf("Metrics() = %+v, want %+v", got, want)
+	}
+}
+
+func TestApp_Routes(t *testing.T) {
+	app := mux.New()
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	auth := func(next mux.Handler) mux.Handler { return next }
+
+	app.Get("/health", handler)
+	app.Group().Post("/login", handler)
+
+	v1 := app.Mount("/v1")
+	v1.Get("/users", handler)
+	v1.Get("/users/{id}", handler, auth)
+	app.Mount("/v1/admin").Delete("/users/{id}", handler, auth)
+	app.HandleNoMiddleware(http.MethodGet, "", "/static/", handler)
+
+	want := []mux.RouteInfo{
+		{Method: http.MethodGet, Pattern: "/health"},
+		{Method: http.MethodPost, Pattern: "/login"},
+		{Method: http.MethodGet, Pattern: "/v1/users"},
+		{Method: http.MethodGet, Pattern: "/v1/users/{id}", Middleware: true},
+		{Method: http.MethodDelete, Pattern: "/v1/admin/users/{id}", Middleware: true},
+		{Method: http.MethodGet, Pattern: "/static/"},
+	}
+
+	got := v1.Routes()
+	if !slices.Equal(got, want) {
+		t.Fatalf("Routes() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// The returned slice is a copy.
+	got[0].Pattern = "/changed"
+	if app.Routes()[0].Pattern != "/health" {
+		t.Fatal("modifying the returned routes changed the App's routes")
+	}
+}
+
+func TestApp_Metrics_UnhandledError(t *testing.T) {
+	app := mux.New(mux.WithLogger(slog.New(slog.DiscardHandler)))
+	app.Get("/err", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf("something went wrong")
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/err")
+	if err != nil {
+		t.Fatalf("GET /err: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := app.Metrics(); got.Requests != 1 || got.Errors != 1 || got.Panics != 0 {
+		t.Fatalf("Metrics() = %+v, want 1 request and 1 error", got)
+	}
+}
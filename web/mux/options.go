@@ -4,6 +4,7 @@ import (
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"net/http/pprof"
 	"reflect"
 	"runtime"
 	"slices"
@@ -16,12 +17,18 @@ type Option func(*options)
 
 // options represents optional parameters.
 type options struct {
-	staticFS   Handler
-	staticPath string
-	tracer     trace.Tracer
-	logger     *slog.Logger
-	globalMW   []Middleware
-	mw         []Middleware
+	staticFS         Handler
+	staticPath       string
+	tracer           trace.Tracer
+	logger           *slog.Logger
+	globalMW         []Middleware
+	mw               []Middleware
+	pprofPath        string
+	pprofMW          []Middleware
+	notFound         Handler
+	methodNotAllowed Handler
+	autoOptions      bool
+	trustForwarded   bool
 }
 
 type ordered struct {
@@ -32,27 +39,20 @@ type ordered struct {
 
 // WithMiddleware auto-categorizes the given middleware by function name,
 // assigns priorities, and splits them into global vs route-level stacks.
-// Known global middleware (CORS, CSRF) runs on every request via ServeHTTP.
-// Known route middleware (Logger, Errors, Panics) and any custom middleware
+// Known global middleware (SecureHeaders, AllowMethods, CleanPath, CORS, CSRF)
+// runs on every request via ServeHTTP.
+// Known route middleware (RequestID, Logger, Errors/ErrorsWithDev, Panics) and any custom middleware
 // run per-route in priority order.
 func WithMiddleware(mw ...Middleware) Option {
 	mwOrdered := make([]ordered, 0, len(mw))
 	globalOrdered := make([]ordered, 0)
 
 	for _, m := range mw {
-		switch name(m) {
-		case "CORS":
-			globalOrdered = append(globalOrdered, ordered{priority: 1, global: true, fn: m})
-		case "CSRF":
-			globalOrdered = append(globalOrdered, ordered{priority: 2, global: true, fn: m})
-		case "Logger":
-			mwOrdered = append(mwOrdered, ordered{priority: 3, global: false, fn: m})
-		case "Errors":
-			mwOrdered = append(mwOrdered, ordered{priority: 4, global: false, fn: m})
-		case "Panics":
-			mwOrdered = append(mwOrdered, ordered{priority: 100, global: false, fn: m})
-		default:
-			mwOrdered = append(mwOrdered, ordered{priority: 5, global: false, fn: m})
+		priority, global := classify(m)
+		if global {
+			globalOrdered = append(globalOrdered, ordered{priority: priority, global: true, fn: m})
+		} else {
+			mwOrdered = append(mwOrdered, ordered{priority: priority, global: false, fn: m})
 		}
 	}
 
@@ -79,6 +79,36 @@ func WithMiddleware(mw ...Middleware) Option {
 	})
 }
 
+// classify returns the priority [WithMiddleware] assigns to mw by its
+// function name, and whether it's global middleware, run before routing.
+func classify(mw Middleware) (priority int, global bool) {
+	switch name(mw) {
+	case "SecureHeaders":
+		return 0, true
+	case "AllowMethods":
+		return 1, true
+	case "CleanPath":
+		return 2, true
+	case "CORS", "CORSWithConfig":
+		return 3, true
+	case "CSRF":
+		return 4, true
+	case "RequestID":
+		return 5, false
+	case "Logger":
+		return 6, false
+	case "Errors", "ErrorsWithDev":
+		return 7, false
+	case "Panics":
+		return 100, false
+	default:
+		return customPriority, false
+	}
+}
+
+// customPriority is the priority of middleware unknown to [WithMiddleware].
+const customPriority = 8
+
 // WithTracer injects the given tracer into the App.
 func WithTracer(tracer trace.Tracer) Option {
 	return Option(func(opts *options) {
@@ -93,6 +123,52 @@ func WithLogger(log *slog.Logger) Option {
 	})
 }
 
+// WithNotFoundHandler serves requests whose path matches no registered
+// route with handler in place of the ServeMux's plain text 404. It runs
+// through the global and App middleware like any route, so an error it
+// returns, such as errs.NewStatus(http.StatusNotFound), is rendered by the
+// Errors middleware. A path registered only for other methods still gets
+// 405 Method Not Allowed, see [WithMethodNotAllowedHandler].
+func WithNotFoundHandler(handler Handler) Option {
+	return Option(func(opts *options) {
+		opts.notFound = handler
+	})
+}
+
+// WithMethodNotAllowedHandler serves requests whose path is registered
+// only for other methods with handler in place of the ServeMux's plain
+// text 405. The Allow header computed by the ServeMux is set before
+// handler runs, through the global and App middleware like any route.
+func WithMethodNotAllowedHandler(handler Handler) Option {
+	return Option(func(opts *options) {
+		opts.methodNotAllowed = handler
+	})
+}
+
+// WithAutoOptions answers OPTIONS requests to a registered path that has
+// no OPTIONS route of its own with 204 No Content and an Allow header
+// listing the path's methods. Routes added with [App.Get] are registered
+// for HEAD too, running the GET handler, whose body the server discards
+// while keeping its headers, so such a path can't have a HEAD route of
+// its own.
+func WithAutoOptions() Option {
+	return Option(func(opts *options) {
+		opts.autoOptions = true
+	})
+}
+
+// WithTrustForwardedHeaders trusts the X-Forwarded-Proto and
+// X-Forwarded-Host headers of requests, as reported by
+// [TrustsForwardedHeaders], for the URLs built by web.AbsoluteURL and
+// web.Redirect, the Secure flag of flash cookies and RequireTLS. Clients
+// can send the headers themselves, so only use it behind a proxy that sets
+// or strips them.
+func WithTrustForwardedHeaders() Option {
+	return Option(func(opts *options) {
+		opts.trustForwarded = true
+	})
+}
+
 // WithStaticFS serves static files from fsys under the given URL path prefix.
 // The prefix is stripped before looking up files in fsys.
 func WithStaticFS(fsys fs.FS, pathPrefix string) Option {
@@ -103,6 +179,41 @@ func WithStaticFS(fsys fs.FS, pathPrefix string) Option {
 	})
 }
 
+// WithPprof registers the net/http/pprof handlers under the given path
+// prefix, defaulting to "/debug/pprof" if empty. The index is served at
+// prefix + "/" alongside cmdline, profile, symbol, trace and the named
+// runtime profiles (allocs, block, goroutine, heap, mutex, threadcreate).
+// The given middleware wraps every pprof route, so they can be gated
+// behind auth. The server's write timeout must exceed the "seconds"
+// requested from the profile and trace endpoints.
+func WithPprof(prefix string, mw ...Middleware) Option {
+	return Option(func(opts *options) {
+		prefix = strings.Trim(prefix, "/")
+		if prefix == "" {
+			prefix = "debug/pprof"
+		}
+		opts.pprofPath = "/" + prefix
+		opts.pprofMW = mw
+	})
+}
+
+// pprofProfiles are the named runtime profiles served by [WithPprof].
+var pprofProfiles = []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"}
+
+// registerPprof wires the pprof handlers onto the app.
+func (a *App) registerPprof(prefix string, mw ...Middleware) {
+	a.HandleRaw(http.MethodGet, "", prefix+"/{$}", http.HandlerFunc(pprof.Index), mw...)
+	a.HandleRaw(http.MethodGet, "", prefix+"/cmdline", http.HandlerFunc(pprof.Cmdline), mw...)
+	a.HandleRaw(http.MethodGet, "", prefix+"/profile", http.HandlerFunc(pprof.Profile), mw...)
+	a.HandleRaw(http.MethodGet, "", prefix+"/symbol", http.HandlerFunc(pprof.Symbol), mw...)
+	a.HandleRaw(http.MethodPost, "", prefix+"/symbol", http.HandlerFunc(pprof.Symbol), mw...)
+	a.HandleRaw(http.MethodGet, "", prefix+"/trace", http.HandlerFunc(pprof.Trace), mw...)
+
+	for _, profile := range pprofProfiles {
+		a.HandleRaw(http.MethodGet, "", prefix+"/"+profile, pprof.Handler(profile), mw...)
+	}
+}
+
 func name(mw Middleware) string {
 	fnName := runtime.FuncForPC(reflect.ValueOf(mw).Pointer()).Name()
 
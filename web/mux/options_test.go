@@ -2,11 +2,20 @@ package mux_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 
+	"github.com/adamwoolhether/httper/web/errs"
 	"github.com/adamwoolhether/httper/web/middleware"
 	"github.com/adamwoolhether/httper/web/mux"
 )
@@ -34,6 +43,92 @@ func TestWithMiddleware_AutoGlobalCORS(t *testing.T) {
 	}
 }
 
+func TestWithMiddleware_GlobalCORSPreflight(t *testing.T) {
+	var called atomic.Bool
+
+	app := mux.New(mux.WithMiddleware(middleware.CORS([]string{"https://example.com"})))
+	app.Get("/items/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		called.Store(true)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	testCases := []struct {
+		name       string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{name: "allowed origin", origin: "https://example.com", wantStatus: http.StatusNoContent, wantOrigin: "https://example.com"},
+		{name: "disallowed origin", origin: "https://evil.com", wantStatus: http.StatusForbidden},
+		{name: "no origin", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodOptions, srv.URL+"/items/42", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+				req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+				req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("OPTIONS /items/42: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tc.wantOrigin)
+			}
+			if tc.wantOrigin != "" {
+				if got := resp.Header.Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodPut) {
+					t.Errorf("Access-Control-Allow-Methods = %q, want it to include PUT", got)
+				}
+				if got := resp.Header.Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Content-Type") {
+					t.Errorf("Access-Control-Allow-Headers = %q, want it to include Content-Type", got)
+				}
+			}
+		})
+	}
+
+	if called.Load() {
+		t.Fatal("GET handler should not run for OPTIONS requests")
+	}
+}
+
+func TestWithMiddleware_AutoGlobalCORSWithConfig(t *testing.T) {
+	cors := middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+	})
+
+	app := mux.New(mux.WithMiddleware(cors))
+	app.Get("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodOptions, "/ping", nil)
+	r.Header.Set("Origin", "https://example.com")
+	app.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Fatalf("Access-Control-Allow-Methods = %q, want %q", got, "GET, POST")
+	}
+}
+
 func TestWithMiddleware_AutoGlobalCSRF(t *testing.T) {
 	app := mux.New(mux.WithMiddleware(middleware.CSRF()))
 	app.Get("/safe", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -57,11 +152,81 @@ func TestWithMiddleware_AutoGlobalCSRF(t *testing.T) {
 	}
 }
 
+func TestWithMiddleware_AutoGlobalCleanPath(t *testing.T) {
+	app := mux.New(mux.WithMiddleware(middleware.CleanPath()))
+	app.Post("/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	// Without CleanPath running before routing, the ServeMux would
+	// redirect this POST instead of routing it.
+	resp, err := http.Post(srv.URL+"/./items", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /./items: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+}
+
+func TestWithTrustForwardedHeaders(t *testing.T) {
+	tests := map[string]struct {
+		opts         []mux.Option
+		wantTrusted  string
+		wantLocation string
+	}{
+		"default": {
+			opts:         []mux.Option{mux.WithMiddleware(middleware.CleanPath())},
+			wantTrusted:  "false",
+			wantLocation: "/items",
+		},
+		"trusted": {
+			opts:         []mux.Option{mux.WithMiddleware(middleware.CleanPath()), mux.WithTrustForwardedHeaders()},
+			wantTrusted:  "true",
+			wantLocation: "https://public.example/items",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			app := mux.New(tc.opts...)
+			app.Get("/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				_, err := io.WriteString(w, strconv.FormatBool(mux.TrustsForwardedHeaders(ctx)))
+				return err
+			})
+
+			get := func(path string) *httptest.ResponseRecorder {
+				r := httptest.NewRequest(http.MethodGet, path, nil)
+				r.Header.Set("X-Forwarded-Proto", "https")
+				r.Header.Set("X-Forwarded-Host", "public.example")
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+				return w
+			}
+
+			if got := get("/items").Body.String(); got != tc.wantTrusted {
+				t.Errorf("route trusts forwarded headers = %s, want %s", got, tc.wantTrusted)
+			}
+
+			// CleanPath runs before routing, redirecting through web.Redirect.
+			if got := get("/./items").Header().Get("Location"); got != tc.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tc.wantLocation)
+			}
+		})
+	}
+}
+
 func TestWithMiddleware_SortsRoute(t *testing.T) {
 	log, _ := newTestLogger(t)
 
 	// Pass Logger, Errors, Panics in reverse priority order.
-	// WithMiddleware should sort them: Logger(3), Errors(4), Panics(100).
+	// WithMiddleware should sort them: Logger(4), Errors(5), Panics(100).
 	app := mux.New(mux.WithMiddleware(
 		middleware.Panics(),
 		middleware.Errors(log),
@@ -198,6 +363,295 @@ func TestWithStaticFS(t *testing.T) {
 	}
 }
 
+func TestWithNotFoundHandler(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := mux.New(
+		mux.WithLogger(log),
+		mux.WithMiddleware(middleware.Errors(log)),
+		mux.WithNotFoundHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return errs.NewStatus(http.StatusNotFound)
+		}),
+	)
+	app.Get("/items", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantJSON   bool
+	}{
+		{name: "unregistered path", method: http.MethodGet, path: "/missing", wantStatus: http.StatusNotFound, wantJSON: true},
+		{name: "unregistered nested path", method: http.MethodPost, path: "/items/1/parts", wantStatus: http.StatusNotFound, wantJSON: true},
+		{name: "registered path", method: http.MethodGet, path: "/items", wantStatus: http.StatusOK},
+		{name: "registered path other method", method: http.MethodPost, path: "/items", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+			if err != nil {
+				t.Fatalf("creating request: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if !tc.wantJSON {
+				return
+			}
+
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Fatalf("Content-Type = %q, want application/json", ct)
+			}
+			var m map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if m["message"] != "Not Found" {
+				t.Fatalf("message = %v, want %q", m["message"], "Not Found")
+			}
+		})
+	}
+
+	if got := app.Metrics().Requests; got != 3 {
+		t.Errorf("Requests = %d, want 3 from the routed and not found requests", got)
+	}
+}
+
+func TestWithNotFoundHandler_Routed(t *testing.T) {
+	app := mux.New(
+		mux.WithNotFoundHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return errs.NewStatus(http.StatusNotFound)
+		}),
+	)
+	app.Get("/gone", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			return errors.New("response writer is not an http.Flusher")
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		_, err := io.WriteString(w, "gone")
+		f.Flush()
+		return err
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/gone", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := w.Body.String(); got != "gone" {
+		t.Fatalf("body = %q, want the route's own response", got)
+	}
+	if !w.Flushed {
+		t.Fatal("expected the route's response to be flushed")
+	}
+}
+
+func TestWithMethodNotAllowedHandler(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	app := mux.New(
+		mux.WithLogger(log),
+		mux.WithMiddleware(middleware.Errors(log)),
+		mux.WithMethodNotAllowedHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return errs.NewStatus(http.StatusMethodNotAllowed)
+		}),
+	)
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	app.Get("/items", handler)
+	app.Delete("/items", handler)
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/items", "", nil)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+	if got, want := resp.Header.Get("Allow"), "DELETE, GET, HEAD"; got != want {
+		t.Fatalf("Allow = %q, want %q", got, want)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	var m map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if m["message"] != "Method Not Allowed" {
+		t.Fatalf("message = %v, want %q", m["message"], "Method Not Allowed")
+	}
+
+	// Unknown paths keep the ServeMux's 404.
+	notFound, err := http.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	notFound.Body.Close()
+	if notFound.StatusCode != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", notFound.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestWithAutoOptions(t *testing.T) {
+	app := mux.New(mux.WithAutoOptions())
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Item", "42")
+		_, err := io.WriteString(w, `{"id":42}`)
+		return err
+	}
+	app.Get("/items/{id}", handler)
+	app.Put("/items/{id}", handler)
+	app.Delete("/items/{id}", handler)
+	app.Post("/custom", handler)
+	app.Handle(http.MethodOptions, "", "/custom", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Allow", "custom")
+		w.WriteHeader(http.StatusOK)
+		return nil
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	send := func(t *testing.T, method, path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatalf("creating request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("options", func(t *testing.T) {
+		resp := send(t, http.MethodOptions, "/items/7")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+		}
+		if got, want := resp.Header.Get("Allow"), "DELETE, GET, HEAD, PUT, OPTIONS"; got != want {
+			t.Fatalf("Allow = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("registered options route", func(t *testing.T) {
+		resp := send(t, http.MethodOptions, "/custom")
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Allow") != "custom" {
+			t.Fatalf("got status %d and Allow %q, want the registered OPTIONS handler", resp.StatusCode, resp.Header.Get("Allow"))
+		}
+	})
+
+	t.Run("options unknown path", func(t *testing.T) {
+		resp := send(t, http.MethodOptions, "/missing")
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+		}
+	})
+
+	t.Run("head route", func(t *testing.T) {
+		want := mux.RouteInfo{Method: http.MethodHead, Pattern: "/items/{id}"}
+		if !slices.Contains(app.Routes(), want) {
+			t.Fatalf("Routes() = %v, want it to contain %v", app.Routes(), want)
+		}
+	})
+
+	t.Run("head", func(t *testing.T) {
+		resp := send(t, http.MethodHead, "/items/7")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" || resp.Header.Get("X-Item") != "42" {
+			t.Fatalf("expected the GET handler's headers, got %v", resp.Header)
+		}
+		if resp.ContentLength != int64(len(`{"id":42}`)) {
+			t.Fatalf("ContentLength = %d, want %d", resp.ContentLength, len(`{"id":42}`))
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("reading body: %v", err)
+		}
+		if len(body) != 0 {
+			t.Fatalf("body = %q, want empty", body)
+		}
+	})
+}
+
+func TestWithPprof(t *testing.T) {
+	var gated atomic.Int32
+	auth := func(handler mux.Handler) mux.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			gated.Add(1)
+			if r.Header.Get("Authorization") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return nil
+			}
+			return handler(ctx, w, r)
+		}
+	}
+
+	app := mux.New(mux.WithPprof("/admin/pprof/", auth))
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	tests := []struct {
+		path       string
+		auth       string
+		wantStatus int
+	}{
+		{path: "/admin/pprof/", auth: "secret", wantStatus: http.StatusOK},
+		{path: "/admin/pprof/cmdline", auth: "secret", wantStatus: http.StatusOK},
+		{path: "/admin/pprof/goroutine?debug=1", auth: "secret", wantStatus: http.StatusOK},
+		{path: "/admin/pprof/heap", auth: "secret", wantStatus: http.StatusOK},
+		{path: "/admin/pprof/heap", wantStatus: http.StatusUnauthorized},
+		{path: "/debug/pprof/", auth: "secret", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		req.Header.Set("Authorization", tt.auth)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tt.path, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+		}
+	}
+
+	if gated.Load() != 5 {
+		t.Errorf("middleware ran %d times, want 5", gated.Load())
+	}
+}
+
 func TestHandleRaw(t *testing.T) {
 	app := mux.New()
 
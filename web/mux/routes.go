@@ -0,0 +1,37 @@
+package mux
+
+import (
+	"slices"
+	"sync"
+)
+
+// RouteInfo describes a route registered on an App.
+type RouteInfo struct {
+	Method     string // Empty for a route matching every method.
+	Pattern    string // Path pattern, including any Mount prefix.
+	Middleware bool   // Whether route-level middleware was given at registration.
+}
+
+// routeTable is shared by an App and every App derived from it via Group
+// and Mount, so Routes lists every route on the mux.
+type routeTable struct {
+	mu     sync.Mutex
+	routes []RouteInfo
+}
+
+func (rt *routeTable) add(info RouteInfo) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.routes = append(rt.routes, info)
+}
+
+// Routes returns the routes registered on the App and the Apps sharing
+// its mux via Group and Mount, in the order they were registered, e.g.
+// to generate docs or serve a debug endpoint.
+func (a *App) Routes() []RouteInfo {
+	a.routes.mu.Lock()
+	defer a.routes.mu.Unlock()
+
+	return slices.Clone(a.routes.routes)
+}
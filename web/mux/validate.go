@@ -0,0 +1,63 @@
+package mux
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Validate checks the App's route middleware, as set by [WithMiddleware]
+// and extended by [App.Use], for stacks that misbehave at runtime, such as
+// Panics added before Errors with Use, so a recovered panic is never
+// rendered. The known middleware must keep the order [WithMiddleware]
+// sorts it in, outermost first: RequestID, Logger, Errors, Panics. Custom
+// middleware may go anywhere. Panics needs Errors, each may appear once,
+// and global middleware such as CORS must be given to [WithMiddleware],
+// as it can't run before routing from the route stack. All problems found
+// are joined in the returned error, which is nil for a valid stack.
+// Middleware passed to a single route isn't checked.
+func (a *App) Validate() error {
+	var errs []error
+
+	seen := make(map[string]bool)
+	var prev string
+	var prevPriority int
+
+	for _, mw := range a.mw {
+		if mw == nil {
+			continue
+		}
+
+		mwName := name(mw)
+		priority, global := classify(mw)
+
+		switch {
+		case global:
+			errs = append(errs, fmt.Errorf("%s is in the route stack, pass it to WithMiddleware to run it before routing", mwName))
+			continue
+		case priority == customPriority:
+			continue
+		}
+
+		kind := mwName
+		if kind == "ErrorsWithDev" {
+			kind = "Errors"
+		}
+		if seen[kind] {
+			errs = append(errs, fmt.Errorf("%s is used more than once", mwName))
+		}
+		seen[kind] = true
+
+		if prev != "" && priority < prevPriority {
+			errs = append(errs, fmt.Errorf("%s is inside %s, it must wrap it", mwName, prev))
+		}
+		if priority >= prevPriority {
+			prev, prevPriority = mwName, priority
+		}
+	}
+
+	if seen["Panics"] && !seen["Errors"] {
+		errs = append(errs, errors.New("Panics is used without Errors, recovered panics won't be rendered"))
+	}
+
+	return errors.Join(errs...)
+}
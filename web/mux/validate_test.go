@@ -0,0 +1,131 @@
+package mux_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web/middleware"
+	"github.com/adamwoolhether/httper/web/mux"
+)
+
+func TestApp_Validate(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	custom := func(handler mux.Handler) mux.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return handler(ctx, w, r)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		app     func() *mux.App
+		wantErr []string
+	}{
+		{
+			name: "empty",
+			app:  func() *mux.App { return mux.New() },
+		},
+		{
+			name: "sorted by WithMiddleware",
+			app: func() *mux.App {
+				return mux.New(mux.WithMiddleware(middleware.Panics(), custom, middleware.Errors(log), middleware.Logger(log), middleware.CORS([]string{"*"})))
+			},
+		},
+		{
+			name: "custom added with Use",
+			app: func() *mux.App {
+				app := mux.New(mux.WithMiddleware(middleware.Logger(log), middleware.Errors(log), middleware.Panics()))
+				app.Use(custom)
+				return app
+			},
+		},
+		{
+			name: "manual stack in order",
+			app: func() *mux.App {
+				app := mux.New()
+				app.Use(middleware.Logger(log), custom, middleware.ErrorsWithDev(log, true), middleware.Panics())
+				return app
+			},
+		},
+		{
+			name: "panics outside errors",
+			app: func() *mux.App {
+				app := mux.New(mux.WithMiddleware(middleware.Logger(log), middleware.Panics()))
+				app.Use(middleware.Errors(log))
+				return app
+			},
+			wantErr: []string{"Errors is inside Panics"},
+		},
+		{
+			name: "logger inside errors",
+			app: func() *mux.App {
+				app := mux.New()
+				app.Use(middleware.Errors(log), middleware.Logger(log))
+				return app
+			},
+			wantErr: []string{"Logger is inside Errors"},
+		},
+		{
+			name: "panics without errors",
+			app: func() *mux.App {
+				app := mux.New()
+				app.Use(middleware.Logger(log), middleware.Panics())
+				return app
+			},
+			wantErr: []string{"Panics is used without Errors"},
+		},
+		{
+			name: "duplicate",
+			app: func() *mux.App {
+				app := mux.New(mux.WithMiddleware(middleware.Logger(log)))
+				app.Use(middleware.Logger(log))
+				return app
+			},
+			wantErr: []string{"Logger is used more than once"},
+		},
+		{
+			name: "global in route stack",
+			app: func() *mux.App {
+				app := mux.New()
+				app.Use(middleware.CORS([]string{"*"}))
+				return app
+			},
+			wantErr: []string{"CORSWithConfig is in the route stack"},
+		},
+		{
+			name: "group inherits stack",
+			app: func() *mux.App {
+				app := mux.New(mux.WithMiddleware(middleware.Errors(log)))
+				group := app.Group()
+				group.Use(middleware.Logger(log))
+				return group
+			},
+			wantErr: []string{"Logger is inside Errors"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.app().Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Validate() = nil, want error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,24 @@
 package web
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
 )
 
 // Param extracts a path parameter by key and returns its string value.
@@ -47,6 +61,40 @@ func ParamInt64(r *http.Request, key string) (int64, error) {
 	return v, nil
 }
 
+// ParamUUID extracts a path parameter by key and parses it as a UUID.
+func ParamUUID(r *http.Request, key string) (uuid.UUID, error) {
+	val := r.PathValue(key)
+	if val == "" {
+		return uuid.Nil, fmt.Errorf("path param[%s] not found", key)
+	}
+
+	v, err := uuid.Parse(val)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("path param[%s] must be UUID: %w", key, err)
+	}
+
+	return v, nil
+}
+
+// ParamOneOf extracts a path parameter by key, matching it case-insensitively
+// against the allowed values and returning the matching allowed value, so
+// "/reports/CSV" yields "csv". A missing or unlisted value returns an
+// [errs.Error] with 400 Bad Request, listing the allowed values.
+func ParamOneOf(r *http.Request, key string, allowed ...string) (string, error) {
+	val := r.PathValue(key)
+	if val == "" {
+		return "", errs.New(http.StatusBadRequest, fmt.Errorf("path param[%s] not found", key))
+	}
+
+	for _, a := range allowed {
+		if strings.EqualFold(val, a) {
+			return a, nil
+		}
+	}
+
+	return "", errs.New(http.StatusBadRequest, fmt.Errorf("path param[%s] must be one of [%s]", key, strings.Join(allowed, " ")))
+}
+
 // QueryString extracts a query parameter by key and returns its string value.
 func QueryString(r *http.Request, key string) (string, error) {
 	val := r.URL.Query().Get(key)
@@ -102,6 +150,173 @@ func QueryInt64(r *http.Request, key string) (int64, error) {
 	return v, nil
 }
 
+// QueryFloat extracts a query parameter by key and parses it as a float64.
+// NaN and infinite values are rejected.
+func QueryFloat(r *http.Request, key string) (float64, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return 0, fmt.Errorf("query param[%s] not found", key)
+	}
+
+	v, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("query param[%s] must be number: %w", key, err)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("query param[%s] must be finite number, got %q", key, val)
+	}
+
+	return v, nil
+}
+
+// QueryTime extracts a query parameter by key and parses it as an RFC3339
+// timestamp, such as "2024-01-02T15:04:05Z".
+func QueryTime(r *http.Request, key string) (time.Time, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return time.Time{}, fmt.Errorf("query param[%s] not found", key)
+	}
+
+	v, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query param[%s] must be RFC3339 timestamp: %w", key, err)
+	}
+
+	return v, nil
+}
+
+// QueryUUID extracts a query parameter by key and parses it as a UUID.
+func QueryUUID(r *http.Request, key string) (uuid.UUID, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return uuid.Nil, fmt.Errorf("query param[%s] not found", key)
+	}
+
+	v, err := uuid.Parse(val)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("query param[%s] must be UUID: %w", key, err)
+	}
+
+	return v, nil
+}
+
+// QueryEnum extracts a query parameter by key, returning it only if it's
+// one of the allowed values. Otherwise an [errs.FieldErrors] keyed by the
+// param is returned, so a missing or invalid value responds with 422.
+func QueryEnum(r *http.Request, key string, allowed []string) (string, error) {
+	return QueryOneOf(r, key, allowed...)
+}
+
+// QueryOneOf is the generic form of [QueryEnum], for string-based enum types.
+func QueryOneOf[T ~string](r *http.Request, key string, allowed ...T) (T, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return "", errs.NewFieldsError(key, fmt.Errorf("query param[%s] not found", key))
+	}
+
+	if !slices.Contains(allowed, T(val)) {
+		opts := make([]string, len(allowed))
+		for i, a := range allowed {
+			opts[i] = string(a)
+		}
+
+		return "", errs.NewFieldsError(key, fmt.Errorf("query param[%s] must be one of [%s]", key, strings.Join(opts, " ")))
+	}
+
+	return T(val), nil
+}
+
+// defaultPageSize is the page size used when [PaginationDefaults] sets none.
+const defaultPageSize = 20
+
+// PaginationDefaults configures [QueryPagination].
+type PaginationDefaults struct {
+	Size    int // Page size when none is requested. Default is 20.
+	MaxSize int // Cap on the requested page size. Zero means no cap.
+}
+
+// Pagination is a validated page of a list, in both page/size
+// and offset/limit terms.
+type Pagination struct {
+	Page   int
+	Size   int
+	Offset int
+	Limit  int
+}
+
+// QueryPagination reads either the page and size, or the offset and limit,
+// query params, applying the defaults and capping the size at MaxSize.
+// Page, size and limit must be positive and offset must not be negative.
+// Invalid values, or mixing the two styles, return an [errs.FieldErrors]
+// keyed by the offending params, so they respond with 422.
+func QueryPagination(r *http.Request, defaults PaginationDefaults) (Pagination, error) {
+	q := r.URL.Query()
+
+	var fields errs.FieldErrors
+	parse := func(key string, minVal, fallback int) int {
+		if !q.Has(key) {
+			return fallback
+		}
+
+		v, err := strconv.Atoi(q.Get(key))
+		if err != nil || v < minVal {
+			fields = append(fields, errs.FieldError{
+				Field: key,
+				Err:   fmt.Sprintf("query param[%s] must be an integer of at least %d", key, minVal),
+			})
+			return fallback
+		}
+
+		return v
+	}
+
+	size := defaults.Size
+	if size <= 0 {
+		size = defaultPageSize
+	}
+
+	usePage := q.Has("page") || q.Has("size")
+	useOffset := q.Has("offset") || q.Has("limit")
+	if usePage && useOffset {
+		return Pagination{}, errs.NewFieldsError("offset", errors.New("query params[page size] cannot be combined with [offset limit]"))
+	}
+
+	var page, offset int
+	if useOffset {
+		size = parse("limit", 1, size)
+		offset = parse("offset", 0, 0)
+	} else {
+		size = parse("size", 1, size)
+		page = parse("page", 1, 1)
+	}
+
+	if defaults.MaxSize > 0 {
+		size = min(size, defaults.MaxSize)
+	}
+
+	if useOffset {
+		page = offset/size + 1
+	} else {
+		if page-1 > math.MaxInt/size {
+			fields = append(fields, errs.FieldError{Field: "page", Err: "query param[page] is too large"})
+		}
+		offset = (page - 1) * size
+	}
+
+	if len(fields) > 0 {
+		return Pagination{}, fields
+	}
+
+	p := Pagination{
+		Page:   page,
+		Size:   size,
+		Offset: offset,
+		Limit:  size,
+	}
+
+	return p, nil
+}
+
 // Decode reads the body of an HTTP request looking for a JSON document. The
 // body is decoded into the provided value.
 // If the provided value is a struct then it is checked for validation tags.
@@ -133,3 +348,275 @@ func DecodeAllowUnknownFields[T any](r *http.Request, val *T) error {
 
 	return nil
 }
+
+// DecodeWithMaxDepth is the same as Decode, but first scans the JSON tokens
+// and rejects documents whose objects and arrays nest deeper than maxDepth,
+// before any value is decoded. The body is buffered in memory for the scan,
+// so public endpoints should also bound its size, e.g. with
+// [http.MaxBytesReader]; the depth limit guards against small but deeply
+// nested payloads that a size limit alone lets through.
+func DecodeWithMaxDepth[T any](r *http.Request, val *T, maxDepth int) error {
+	if maxDepth < 1 {
+		return fmt.Errorf("decode: max depth must be positive, got %d", maxDepth)
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("decode: reading body: %w", err)
+	}
+
+	if err := checkDepth(data, maxDepth); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(val); err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	if err := Validate(val); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DecodeQuery populates the fields of the struct val points to from the
+// request's query params, naming each field's param with a `query` tag, such
+// as `query:"page"`. Fields without the tag are left alone, as are those
+// whose param is missing or empty. Supported field types are strings, bools,
+// signed integers, time.Duration, and time.Time, parsed as RFC 3339. Values
+// that don't parse return an [errs.FieldErrors] keyed by param, so they
+// respond with 422, after which val is validated as by [Decode].
+func DecodeQuery[T any](r *http.Request, val *T) error {
+	rv := reflect.ValueOf(val).Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("decode query: %T is not a struct", *val)
+	}
+
+	q := r.URL.Query()
+	rt := rv.Type()
+
+	var fields errs.FieldErrors
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		key := sf.Tag.Get("query")
+		if key == "" || key == "-" || !sf.IsExported() {
+			continue
+		}
+
+		raw := q.Get(key)
+		if raw == "" {
+			continue
+		}
+
+		msg, err := setQueryField(rv.Field(i), raw)
+		if err != nil {
+			return fmt.Errorf("decode query: field %s: %w", sf.Name, err)
+		}
+		if msg != "" {
+			fields = append(fields, errs.FieldError{
+				Field: key,
+				Err:   fmt.Sprintf("query param[%s] must be %s", key, msg),
+			})
+		}
+	}
+
+	if len(fields) > 0 {
+		return fields
+	}
+
+	if err := Validate(val); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeFor[time.Time]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// setQueryField parses raw into field by its type. If raw doesn't parse,
+// it returns what the param must be instead; an error is returned for
+// unsupported field types.
+func setQueryField(field reflect.Value, raw string) (string, error) {
+	switch field.Type() {
+	case timeType:
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return "an RFC 3339 time", nil
+		}
+		field.Set(reflect.ValueOf(t))
+		return "", nil
+
+	case durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return "a duration", nil
+		}
+		field.SetInt(int64(d))
+		return "", nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "boolean", nil
+		}
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return "integer", nil
+		}
+		field.SetInt(n)
+
+	default:
+		return "", fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return "", nil
+}
+
+// checkDepth walks the JSON tokens in data, erroring once
+// the object/array nesting exceeds maxDepth.
+func checkDepth(data []byte, maxDepth int) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	depth := 0
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			continue
+		}
+
+		switch delim {
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("exceeds max nesting depth of %d", maxDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+}
+
+// AbsoluteURL builds an absolute URL for path from the incoming request.
+// The scheme is derived from the TLS state and the host from r.Host, or,
+// for an App built with mux.WithTrustForwardedHeaders, from
+// X-Forwarded-Proto and X-Forwarded-Host when set by a proxy. A scheme
+// other than http or https and a host that isn't a well-formed host[:port]
+// are ignored. For HTTP/1.0 requests without a Host header, the server's
+// local address is used instead.
+func AbsoluteURL(r *http.Request, path string) string {
+	scheme := requestScheme(r)
+
+	host := forwardedHost(r)
+	if host == "" {
+		host = r.Host
+	}
+	if host == "" {
+		host = r.URL.Host
+	}
+	if host == "" {
+		if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+			host = addr.String()
+		}
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return fmt.Sprintf("%s://%s%s", scheme, host, path)
+}
+
+// requestScheme returns "https" if r arrived over TLS, or claims to have
+// via a trusted X-Forwarded-Proto, and "http" otherwise.
+func requestScheme(r *http.Request) string {
+	if mux.TrustsForwardedHeaders(r.Context()) {
+		switch proto := strings.ToLower(firstHeaderValue(r, "X-Forwarded-Proto")); proto {
+		case "http", "https":
+			return proto
+		}
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// forwardedHost returns the trusted X-Forwarded-Host of r, or "" if it's
+// not trusted, not set, or not a well-formed host[:port].
+func forwardedHost(r *http.Request) string {
+	if !mux.TrustsForwardedHeaders(r.Context()) {
+		return ""
+	}
+
+	host := firstHeaderValue(r, "X-Forwarded-Host")
+	if !validHost(host) {
+		return ""
+	}
+
+	return host
+}
+
+// validHost reports whether host is a host name, IPv4 address or bracketed
+// IPv6 address, with an optional port, so it can't carry userinfo, a path
+// or another URL into one built from it.
+func validHost(host string) bool {
+	name := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+			return false
+		}
+		name = h
+	}
+
+	if strings.HasPrefix(host, "[") {
+		ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(name, "["), "]"))
+		return ip != nil && ip.To4() == nil
+	}
+
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for label := range strings.SplitSeq(name, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for i := range len(label) {
+			c := label[i]
+			if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// firstHeaderValue returns the first entry of a possibly comma-separated header.
+func firstHeaderValue(r *http.Request, key string) string {
+	v, _, _ := strings.Cut(r.Header.Get(key), ",")
+	return strings.TrimSpace(v)
+}
@@ -1,12 +1,22 @@
 package web_test
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/adamwoolhether/httper/web"
+	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
 )
 
 // ---- Param ----
@@ -67,6 +77,47 @@ func TestParamInt_Missing(t *testing.T) {
 	}
 }
 
+// ---- ParamOneOf ----
+
+func TestParamOneOf(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/reports/CSV", nil)
+	r.SetPathValue("format", "CSV")
+
+	val, err := web.ParamOneOf(r, "format", "pdf", "csv", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != "csv" {
+		t.Fatalf("val = %q, want %q", val, "csv")
+	}
+}
+
+func TestParamOneOf_Invalid(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/reports/xml", nil)
+	r.SetPathValue("format", "xml")
+
+	_, err := web.ParamOneOf(r, "format", "pdf", "csv", "json")
+	appErr, ok := errors.AsType[*errs.Error](err)
+	if !ok {
+		t.Fatalf("expected *errs.Error, got %v", err)
+	}
+	if appErr.Code != http.StatusBadRequest {
+		t.Fatalf("code = %d, want %d", appErr.Code, http.StatusBadRequest)
+	}
+	if appErr.Message != "path param[format] must be one of [pdf csv json]" {
+		t.Fatalf("message = %q", appErr.Message)
+	}
+}
+
+func TestParamOneOf_Missing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/reports", nil)
+
+	_, err := web.ParamOneOf(r, "format", "pdf", "csv", "json")
+	if appErr, ok := errors.AsType[*errs.Error](err); !ok || appErr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 *errs.Error for missing param, got %v", err)
+	}
+}
+
 // ---- ParamInt64 ----
 
 func TestParamInt64(t *testing.T) {
@@ -101,6 +152,47 @@ func TestParamInt64_Missing(t *testing.T) {
 	}
 }
 
+// ---- ParamUUID ----
+
+func TestParamUUID(t *testing.T) {
+	id := "6f1c2f4e-8a3b-4d5e-9f01-23456789abcd"
+	r := httptest.NewRequest(http.MethodGet, "/items/"+id, nil)
+	r.SetPathValue("id", id)
+
+	val, err := web.ParamUUID(r, "id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != uuid.MustParse(id) {
+		t.Fatalf("val = %s, want %s", val, id)
+	}
+}
+
+func TestParamUUID_Invalid(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items/not-a-uuid", nil)
+	r.SetPathValue("id", "not-a-uuid")
+
+	val, err := web.ParamUUID(r, "id")
+	if err == nil {
+		t.Fatal("expected error for malformed UUID")
+	}
+	if val != uuid.Nil {
+		t.Fatalf("val = %s, want nil UUID", val)
+	}
+	if !strings.Contains(err.Error(), "path param[id] must be UUID") {
+		t.Fatalf("error = %q, want it to name the param", err)
+	}
+}
+
+func TestParamUUID_Missing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	_, err := web.ParamUUID(r, "id")
+	if err == nil {
+		t.Fatal("expected error for missing param")
+	}
+}
+
 // ---- QueryString ----
 
 func TestQueryString(t *testing.T) {
@@ -220,6 +312,219 @@ func TestQueryInt64_Missing(t *testing.T) {
 	}
 }
 
+// ---- QueryFloat ----
+
+func TestQueryFloat(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?min_price=19.99", nil)
+
+	val, err := web.QueryFloat(r, "min_price")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != 19.99 {
+		t.Fatalf("val = %v, want 19.99", val)
+	}
+}
+
+func TestQueryFloat_Invalid(t *testing.T) {
+	for _, v := range []string{"cheap", "NaN", "Inf"} {
+		r := httptest.NewRequest(http.MethodGet, "/items?min_price="+v, nil)
+
+		_, err := web.QueryFloat(r, "min_price")
+		if err == nil {
+			t.Fatalf("expected error for %q", v)
+		}
+		if !strings.Contains(err.Error(), "query param[min_price] must be") {
+			t.Fatalf("error = %q, want it to name the param", err)
+		}
+	}
+}
+
+func TestQueryFloat_Missing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	_, err := web.QueryFloat(r, "min_price")
+	if err == nil {
+		t.Fatal("expected error for missing query param")
+	}
+}
+
+// ---- QueryTime ----
+
+func TestQueryTime(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?since=2024-01-02T15:04:05%2B02:00", nil)
+
+	val, err := web.QueryTime(r, "since")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2024, 1, 2, 13, 4, 5, 0, time.UTC)
+	if !val.Equal(want) {
+		t.Fatalf("val = %v, want %v", val, want)
+	}
+}
+
+func TestQueryTime_Invalid(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?since=2024-01-02", nil)
+
+	_, err := web.QueryTime(r, "since")
+	if err == nil {
+		t.Fatal("expected error for non-RFC3339 time")
+	}
+	if !strings.Contains(err.Error(), "query param[since] must be RFC3339 timestamp") {
+		t.Fatalf("error = %q, want it to name the param and format", err)
+	}
+}
+
+func TestQueryTime_Missing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	_, err := web.QueryTime(r, "since")
+	if err == nil {
+		t.Fatal("expected error for missing query param")
+	}
+}
+
+// ---- QueryUUID ----
+
+func TestQueryUUID(t *testing.T) {
+	id := "6f1c2f4e-8a3b-4d5e-9f01-23456789abcd"
+	r := httptest.NewRequest(http.MethodGet, "/items?owner="+id, nil)
+
+	val, err := web.QueryUUID(r, "owner")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != uuid.MustParse(id) {
+		t.Fatalf("val = %s, want %s", val, id)
+	}
+}
+
+func TestQueryUUID_Invalid(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?owner=6f1c2f4e-zzzz", nil)
+
+	_, err := web.QueryUUID(r, "owner")
+	if err == nil {
+		t.Fatal("expected error for malformed UUID")
+	}
+	if !strings.Contains(err.Error(), "query param[owner] must be UUID") {
+		t.Fatalf("error = %q, want it to name the param", err)
+	}
+}
+
+func TestQueryUUID_Missing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	_, err := web.QueryUUID(r, "owner")
+	if err == nil {
+		t.Fatal("expected error for missing query param")
+	}
+}
+
+func TestQueryEnum(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?sort=desc", nil)
+
+	val, err := web.QueryEnum(r, "sort", []string{"asc", "desc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != "desc" {
+		t.Fatalf("val = %q, want %q", val, "desc")
+	}
+}
+
+func TestQueryEnum_Invalid(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?sort=sideways", nil)
+
+	_, err := web.QueryEnum(r, "sort", []string{"asc", "desc"})
+	fields := errs.GetFieldErrors(err)
+	if fields == nil {
+		t.Fatalf("expected field errors, got %v", err)
+	}
+	if got := fields.Fields()["sort"]; got != "query param[sort] must be one of [asc desc]" {
+		t.Fatalf("field error = %q", got)
+	}
+}
+
+func TestQueryEnum_Missing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+	_, err := web.QueryEnum(r, "sort", []string{"asc", "desc"})
+	if !errs.IsFieldErrors(err) {
+		t.Fatalf("expected field errors for missing query param, got %v", err)
+	}
+}
+
+func TestQueryOneOf(t *testing.T) {
+	type order string
+
+	r := httptest.NewRequest(http.MethodGet, "/items?sort=asc", nil)
+
+	val, err := web.QueryOneOf(r, "sort", order("asc"), order("desc"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val != order("asc") {
+		t.Fatalf("val = %q, want %q", val, "asc")
+	}
+}
+
+// ---- QueryPagination ----
+
+func TestQueryPagination(t *testing.T) {
+	defaults := web.PaginationDefaults{Size: 25, MaxSize: 100}
+
+	tests := []struct {
+		name   string
+		query  string
+		defs   web.PaginationDefaults
+		exp    web.Pagination
+		fields []string
+	}{
+		{name: "defaults", query: "", defs: defaults, exp: web.Pagination{Page: 1, Size: 25, Offset: 0, Limit: 25}},
+		{name: "zero defaults", query: "", exp: web.Pagination{Page: 1, Size: 20, Offset: 0, Limit: 20}},
+		{name: "page and size", query: "page=3&size=10", defs: defaults, exp: web.Pagination{Page: 3, Size: 10, Offset: 20, Limit: 10}},
+		{name: "page only", query: "page=2", defs: defaults, exp: web.Pagination{Page: 2, Size: 25, Offset: 25, Limit: 25}},
+		{name: "size capped", query: "size=500", defs: defaults, exp: web.Pagination{Page: 1, Size: 100, Offset: 0, Limit: 100}},
+		{name: "offset and limit", query: "offset=40&limit=20", defs: defaults, exp: web.Pagination{Page: 3, Size: 20, Offset: 40, Limit: 20}},
+		{name: "offset only", query: "offset=10", defs: defaults, exp: web.Pagination{Page: 1, Size: 25, Offset: 10, Limit: 25}},
+		{name: "limit capped", query: "limit=1000", defs: defaults, exp: web.Pagination{Page: 1, Size: 100, Offset: 0, Limit: 100}},
+		{name: "zero page", query: "page=0", defs: defaults, fields: []string{"page"}},
+		{name: "negative size", query: "size=-1", defs: defaults, fields: []string{"size"}},
+		{name: "non-integer", query: "page=x&size=y", defs: defaults, fields: []string{"page", "size"}},
+		{name: "negative offset", query: "offset=-5&limit=0", defs: defaults, fields: []string{"limit", "offset"}},
+		{name: "page overflow", query: "page=9223372036854775807", defs: defaults, fields: []string{"page"}},
+		{name: "mixed styles", query: "page=2&limit=10", defs: defaults, fields: []string{"offset"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil)
+
+			got, err := web.QueryPagination(r, tt.defs)
+			if tt.fields != nil {
+				fields := errs.GetFieldErrors(err)
+				if fields == nil {
+					t.Fatalf("expected field errors, got %v", err)
+				}
+				for _, f := range tt.fields {
+					if _, ok := fields.Fields()[f]; !ok {
+						t.Errorf("expected field error for %q, got %v", f, fields)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.exp {
+				t.Fatalf("pagination = %+v, want %+v", got, tt.exp)
+			}
+		})
+	}
+}
+
 // ---- Decode ----
 
 type testPayload struct {
@@ -286,3 +591,224 @@ func TestDecodeAllowUnknownFields(t *testing.T) {
 		t.Fatalf("Name = %q, want %q", p.Name, "Bob")
 	}
 }
+
+func TestDecodeWithMaxDepth(t *testing.T) {
+	tests := map[string]struct {
+		body     string
+		maxDepth int
+		wantErr  bool
+	}{
+		"within limit":    {body: `{"name":"Alice","email":"alice@example.com"}`, maxDepth: 1},
+		"exceeds limit":   {body: `{"name":"Alice","email":"alice@example.com","tags":[[["x"]]]}`, maxDepth: 3, wantErr: true},
+		"deeply nested":   {body: strings.Repeat("[", 10000) + strings.Repeat("]", 10000), maxDepth: 32, wantErr: true},
+		"invalid depth":   {body: `{}`, maxDepth: 0, wantErr: true},
+		"unknown field":   {body: `{"name":"Alice","email":"alice@example.com","extra":1}`, maxDepth: 8, wantErr: true},
+		"validation fail": {body: `{"name":"","email":"nope"}`, maxDepth: 8, wantErr: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+
+			var p testPayload
+			err := web.DecodeWithMaxDepth(r, &p, tt.maxDepth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type testFilters struct {
+	Query   string        `query:"q" validate:"required"`
+	Page    int           `query:"page" validate:"omitempty,max=100"`
+	Cursor  int64         `query:"cursor"`
+	Active  bool          `query:"active"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	Ignored string
+}
+
+func TestDecodeQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?q=go&page=2&cursor=9000000000&active=true&since=2026-01-02T03:04:05Z&timeout=1m30s&Ignored=x", nil)
+
+	var f testFilters
+	if err := web.DecodeQuery(r, &f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := testFilters{
+		Query:   "go",
+		Page:    2,
+		Cursor:  9000000000,
+		Active:  true,
+		Since:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Timeout: 90 * time.Second,
+	}
+	if f != want {
+		t.Fatalf("got %+v, want %+v", f, want)
+	}
+}
+
+func TestDecodeQuery_OptionalMissing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?q=go", nil)
+
+	var f testFilters
+	if err := web.DecodeQuery(r, &f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f != (testFilters{Query: "go"}) {
+		t.Fatalf("expected only Query set, got %+v", f)
+	}
+}
+
+func TestDecodeQuery_Errors(t *testing.T) {
+	tests := map[string]struct {
+		target     string
+		wantFields []string
+	}{
+		"bad int":         {target: "/?q=go&page=two", wantFields: []string{"page"}},
+		"bad int64":       {target: "/?q=go&cursor=1.5", wantFields: []string{"cursor"}},
+		"bad bool":        {target: "/?q=go&active=maybe", wantFields: []string{"active"}},
+		"bad time":        {target: "/?q=go&since=yesterday", wantFields: []string{"since"}},
+		"bad duration":    {target: "/?q=go&timeout=soon", wantFields: []string{"timeout"}},
+		"several bad":     {target: "/?q=go&page=x&active=y", wantFields: []string{"page", "active"}},
+		"required":        {target: "/?page=1", wantFields: []string{"q"}},
+		"validation rule": {target: "/?q=go&page=500", wantFields: []string{"page"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+
+			var f testFilters
+			err := web.DecodeQuery(r, &f)
+
+			fields := errs.GetFieldErrors(err)
+			if fields == nil {
+				t.Fatalf("expected FieldErrors, got %v", err)
+			}
+
+			var got []string
+			for _, fe := range fields {
+				got = append(got, fe.Field)
+			}
+			if !slices.Equal(got, tt.wantFields) {
+				t.Fatalf("fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestDecodeQuery_UnsupportedType(t *testing.T) {
+	var dst struct {
+		Tags []string `query:"tags"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?tags=a", nil)
+	err := web.DecodeQuery(r, &dst)
+	if err == nil || errs.IsFieldErrors(err) {
+		t.Fatalf("expected a plain error for an unsupported type, got %v", err)
+	}
+}
+
+// ---- AbsoluteURL ----
+
+func TestAbsoluteURL(t *testing.T) {
+	forwarded := func(proto, host string) func(r *http.Request) {
+		return func(r *http.Request) {
+			r.Header.Set("X-Forwarded-Proto", proto)
+			r.Header.Set("X-Forwarded-Host", host)
+		}
+	}
+
+	tests := map[string]struct {
+		setup func(r *http.Request)
+		trust bool
+		path  string
+		want  string
+	}{
+		"plain": {
+			path: "/items",
+			want: "http://example.com/items",
+		},
+		"tls": {
+			setup: func(r *http.Request) { r.TLS = &tls.ConnectionState{} },
+			path:  "/items",
+			want:  "https://example.com/items",
+		},
+		"forwarded": {
+			setup: forwarded("https, http", "public.example, internal"),
+			trust: true,
+			path:  "/items",
+			want:  "https://public.example/items",
+		},
+		"forwardedPort": {
+			setup: forwarded("HTTP", "public.example:8443"),
+			trust: true,
+			path:  "/items",
+			want:  "http://public.example:8443/items",
+		},
+		"forwardedIPv6": {
+			setup: forwarded("https", "[2001:db8::1]:443"),
+			trust: true,
+			path:  "/items",
+			want:  "https://[2001:db8::1]:443/items",
+		},
+		"forwardedUntrusted": {
+			setup: forwarded("https", "evil.example"),
+			path:  "/items",
+			want:  "http://example.com/items",
+		},
+		"forwardedBadScheme": {
+			setup: forwarded("javascript", "public.example"),
+			trust: true,
+			path:  "/items",
+			want:  "http://public.example/items",
+		},
+		"forwardedBadHost": {
+			setup: forwarded("https", "evil.example/path?"),
+			trust: true,
+			path:  "/items",
+			want:  "https://example.com/items",
+		},
+		"forwardedUserinfo": {
+			setup: forwarded("https", "example.com@evil.example"),
+			trust: true,
+			path:  "/items",
+			want:  "https://example.com/items",
+		},
+		"forwardedBadPort": {
+			setup: forwarded("https", "public.example:99999"),
+			trust: true,
+			path:  "/items",
+			want:  "https://example.com/items",
+		},
+		"missingHost": {
+			setup: func(r *http.Request) {
+				r.Host = ""
+				r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/1.0", 1, 0
+				ctx := context.WithValue(r.Context(), http.LocalAddrContextKey, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 8080})
+				*r = *r.WithContext(ctx)
+			},
+			path: "items",
+			want: "http://10.0.0.1:8080/items",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.trust {
+				r = r.WithContext(mux.NewTestContext(mux.WithTestTrustForwardedHeaders()))
+			}
+			if tc.setup != nil {
+				tc.setup(r)
+			}
+
+			if got := web.AbsoluteURL(r, tc.path); got != tc.want {
+				t.Errorf("AbsoluteURL = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
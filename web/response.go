@@ -3,8 +3,13 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/adamwoolhether/httper/web/errs"
 	"github.com/adamwoolhether/httper/web/mux"
@@ -34,6 +39,97 @@ func RespondJSON(ctx context.Context, w http.ResponseWriter, statusCode int, dat
 	return nil
 }
 
+// RespondXML to an HTTP request, setting the status code and body if any.
+// The body is v marshaled by encoding/xml, preceded by the XML declaration.
+func RespondXML(ctx context.Context, w http.ResponseWriter, statusCode int, v any) error {
+	mux.SetStatusCode(ctx, statusCode)
+
+	if statusCode == http.StatusNoContent {
+		w.WriteHeader(statusCode)
+		return nil
+	}
+
+	xmlData, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(statusCode)
+
+	if _, err = w.Write(append([]byte(xml.Header), xmlData...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Respond writes v as XML via [RespondXML] if the request's Accept header
+// prefers application/xml or text/xml over application/json, and as JSON
+// via [RespondJSON] otherwise, including when Accept is missing, "*/*", or
+// names neither. Preference follows the q-values, with ties going to JSON.
+func Respond(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int, v any) error {
+	w.Header().Add("Vary", "Accept")
+
+	if prefersXML(r.Header.Values("Accept")) {
+		return RespondXML(ctx, w, statusCode, v)
+	}
+
+	return RespondJSON(ctx, w, statusCode, v)
+}
+
+// prefersXML reports whether the Accept header values rank XML above JSON.
+func prefersXML(accept []string) bool {
+	var jsonQ, xmlQ float64
+	for _, header := range accept {
+		for _, part := range strings.Split(header, ",") {
+			mediaType, params, _ := strings.Cut(part, ";")
+
+			q := 1.0
+			for _, param := range strings.Split(params, ";") {
+				key, val, _ := strings.Cut(param, "=")
+				if strings.TrimSpace(key) == "q" {
+					if parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+
+			switch strings.ToLower(strings.TrimSpace(mediaType)) {
+			case "application/json":
+				jsonQ = max(jsonQ, q)
+			case "application/xml", "text/xml":
+				xmlQ = max(xmlQ, q)
+			}
+		}
+	}
+
+	return xmlQ > jsonQ
+}
+
+// RespondCached is [RespondJSON] with a Cache-Control header letting
+// browsers and shared caches such as CDNs keep the response for maxAge,
+// rounded down to whole seconds. With immutable, the content is marked as
+// never changing at its URL, e.g. a content-addressed resource, so caches
+// serve it without revalidating and no ETag is needed; any ETag already set
+// is removed. maxAge must be positive.
+func RespondCached(ctx context.Context, w http.ResponseWriter, statusCode int, data any, maxAge time.Duration, immutable bool) error {
+	seconds := int64(maxAge / time.Second)
+	if seconds <= 0 {
+		return fmt.Errorf("invalid cache max age: %s", maxAge)
+	}
+
+	cacheControl := "public, max-age=" + strconv.FormatInt(seconds, 10)
+	if immutable {
+		cacheControl += ", immutable"
+		w.Header().Del("ETag")
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+
+	return RespondJSON(ctx, w, statusCode, data)
+}
+
 // RespondError writes a structured JSON error response using the
 // status code and message from the given *errs.Error.
 func RespondError(ctx context.Context, w http.ResponseWriter, err *errs.Error) error {
@@ -41,15 +137,72 @@ func RespondError(ctx context.Context, w http.ResponseWriter, err *errs.Error) e
 }
 
 // Redirect issues an HTTP redirect to the given URL. The status code
-// must be in the 3xx range or an error is returned.
-func Redirect(w http.ResponseWriter, r *http.Request, url string, code int) error {
+// must be in the 3xx range or an error is returned. Relative URLs are
+// sent relative, for the client to resolve against the URL it requested.
+// For an App built with mux.WithTrustForwardedHeaders, they're made
+// absolute via [AbsoluteURL] instead, with the scheme and host the proxy
+// forwarded.
+func Redirect(w http.ResponseWriter, r *http.Request, target string, code int) error {
 	if code < 300 || code > 399 {
 		return fmt.Errorf("invalid redirect code: %d", code)
 	}
 
+	// Untrusted, the host is the client's Host header, which a cache in
+	// front could be poisoned with, and the scheme may not be the client's.
+	u, err := url.Parse(target)
+	if err == nil && u.Scheme == "" && u.Host == "" && mux.TrustsForwardedHeaders(r.Context()) {
+		ref := r.URL.ResolveReference(u)
+		target = AbsoluteURL(r, ref.RequestURI())
+		if ref.Fragment != "" {
+			target += "#" + ref.EscapedFragment()
+		}
+	}
+
 	mux.SetStatusCode(r.Context(), code)
 
-	http.Redirect(w, r, url, code)
+	http.Redirect(w, r, target, code)
+
+	return nil
+}
+
+// SetPaginationLinks sets an RFC 8288 Link header with the first, prev, next
+// and last pages of a list, and X-Total-Count to total. The links are the
+// request URL made absolute via [AbsoluteURL] with the pagination params
+// replaced, keeping the offset/limit style if the request used it, as
+// accepted by [QueryPagination]. prev and next are omitted at the bounds.
+// Page and size must be positive and total must not be negative.
+func SetPaginationLinks(w http.ResponseWriter, r *http.Request, page, size, total int) error {
+	if page < 1 || size < 1 || total < 0 {
+		return fmt.Errorf("invalid pagination: page %d, size %d, total %d", page, size, total)
+	}
+
+	last := max(1, (total-1)/size+1)
+
+	q := r.URL.Query()
+	useOffset := q.Has("offset") || q.Has("limit")
+	pageURL := func(p int) string {
+		if useOffset {
+			q.Set("offset", strconv.Itoa((p-1)*size))
+			q.Set("limit", strconv.Itoa(size))
+		} else {
+			q.Set("page", strconv.Itoa(p))
+			q.Set("size", strconv.Itoa(size))
+		}
+
+		return AbsoluteURL(r, r.URL.EscapedPath()) + "?" + q.Encode()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(1))}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(min(page-1, last))))
+	}
+	if page < last {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(last)))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 
 	return nil
 }
@@ -3,13 +3,16 @@ package web_test
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/adamwoolhether/httper/web"
 	"github.com/adamwoolhether/httper/web/errs"
+	"github.com/adamwoolhether/httper/web/mux"
 )
 
 func TestRespondJSON(t *testing.T) {
@@ -55,6 +58,174 @@ func TestRespondJSON_NoContent(t *testing.T) {
 	}
 }
 
+func TestRespondXML(t *testing.T) {
+	type status struct {
+		XMLName xml.Name `xml:"status"`
+		Value   string   `xml:"value"`
+	}
+
+	w := httptest.NewRecorder()
+	ctx := mux.NewTestContext()
+
+	err := web.RespondXML(ctx, w, http.StatusCreated, status{Value: "ok"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Fatalf("Content-Type = %q, want %q", ct, "application/xml")
+	}
+	if got := mux.GetValues(ctx).StatusCode; got != http.StatusCreated {
+		t.Fatalf("tracked status = %d, want %d", got, http.StatusCreated)
+	}
+
+	want := xml.Header + "<status><value>ok</value></status>"
+	if got := w.Body.String(); got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+}
+
+func TestRespondXML_NoContent(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	err := web.RespondXML(context.Background(), w, http.StatusNoContent, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("body should be empty, got %d bytes", w.Body.Len())
+	}
+}
+
+func TestRespondXML_MarshalError(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	if err := web.RespondXML(context.Background(), w, http.StatusOK, map[string]string{"k": "v"}); err == nil {
+		t.Fatal("expected error for a value encoding/xml can't marshal")
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("body should be empty on error, got %q", w.Body.String())
+	}
+}
+
+func TestRespond(t *testing.T) {
+	type item struct {
+		XMLName xml.Name `json:"-" xml:"item"`
+		ID      int      `json:"id" xml:"id"`
+	}
+
+	tests := []struct {
+		name     string
+		accept   []string
+		wantType string
+		wantBody string
+	}{
+		{name: "xml", accept: []string{"application/xml"}, wantType: "application/xml", wantBody: xml.Header + "<item><id>7</id></item>"},
+		{name: "text xml", accept: []string{"text/xml"}, wantType: "application/xml", wantBody: xml.Header + "<item><id>7</id></item>"},
+		{name: "json", accept: []string{"application/json"}, wantType: "application/json", wantBody: `{"id":7}`},
+		{name: "missing", wantType: "application/json", wantBody: `{"id":7}`},
+		{name: "wildcard", accept: []string{"*/*"}, wantType: "application/json", wantBody: `{"id":7}`},
+		{name: "unsupported", accept: []string{"text/html"}, wantType: "application/json", wantBody: `{"id":7}`},
+		{name: "q prefers xml", accept: []string{"application/json;q=0.5, application/xml"}, wantType: "application/xml", wantBody: xml.Header + "<item><id>7</id></item>"},
+		{name: "q prefers json", accept: []string{"application/xml;q=0.8", "application/json;q=0.9"}, wantType: "application/json", wantBody: `{"id":7}`},
+		{name: "tie goes to json", accept: []string{"application/xml, application/json"}, wantType: "application/json", wantBody: `{"id":7}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/items/7", nil)
+			for _, v := range tt.accept {
+				r.Header.Add("Accept", v)
+			}
+			ctx := mux.NewTestContext()
+
+			if err := web.Respond(ctx, w, r, http.StatusOK, item{ID: 7}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if got := mux.GetValues(ctx).StatusCode; got != http.StatusOK {
+				t.Fatalf("tracked status = %d, want %d", got, http.StatusOK)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.wantType {
+				t.Fatalf("Content-Type = %q, want %q", ct, tt.wantType)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Fatalf("body = %q, want %q", got, tt.wantBody)
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept" {
+				t.Errorf("Vary = %q, want %q", vary, "Accept")
+			}
+		})
+	}
+}
+
+func TestRespondCached(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxAge    time.Duration
+		immutable bool
+		want      string
+	}{
+		{name: "immutable", maxAge: 365 * 24 * time.Hour, immutable: true, want: "public, max-age=31536000, immutable"},
+		{name: "mutable", maxAge: 5 * time.Minute, want: "public, max-age=300"},
+		{name: "rounds down", maxAge: 1500 * time.Millisecond, want: "public, max-age=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set("ETag", `"abc"`)
+
+			err := web.RespondCached(context.Background(), w, http.StatusOK, map[string]string{"id": "1"}, tt.maxAge, tt.immutable)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", w.Code)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %q", got)
+			}
+
+			etag := w.Header().Get("ETag")
+			if tt.immutable && etag != "" {
+				t.Errorf("expected ETag removed for immutable content, got %q", etag)
+			}
+			if !tt.immutable && etag == "" {
+				t.Error("expected ETag kept for mutable content")
+			}
+		})
+	}
+}
+
+func TestRespondCached_InvalidMaxAge(t *testing.T) {
+	for _, maxAge := range []time.Duration{0, -time.Minute, 500 * time.Millisecond} {
+		w := httptest.NewRecorder()
+
+		if err := web.RespondCached(context.Background(), w, http.StatusOK, nil, maxAge, true); err == nil {
+			t.Errorf("expected error for max age %s", maxAge)
+		}
+		if got := w.Header().Get("Cache-Control"); got != "" {
+			t.Errorf("expected no Cache-Control for max age %s, got %q", maxAge, got)
+		}
+	}
+}
+
 func TestRespondError(t *testing.T) {
 	w := httptest.NewRecorder()
 	ctx := context.Background()
@@ -98,6 +269,46 @@ func TestRedirect(t *testing.T) {
 	}
 }
 
+func TestRedirect_Locations(t *testing.T) {
+	forwarded := map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "public.example"}
+
+	tests := map[string]struct {
+		target  string
+		headers map[string]string
+		trust   bool
+		want    string
+	}{
+		"absolutePath":      {target: "/new?x=1", want: "/new?x=1"},
+		"relativePath":      {target: "sibling", want: "/a/sibling"},
+		"absoluteURL":       {target: "https://other.example/x", want: "https://other.example/x"},
+		"forwardedProxy":    {target: "/new", headers: forwarded, trust: true, want: "https://public.example/new"},
+		"forwardedRelative": {target: "sibling?x=1#top", headers: forwarded, trust: true, want: "https://public.example/a/sibling?x=1#top"},
+		"trustedDirect":     {target: "/new", trust: true, want: "http://example.com/new"},
+		"forwardedSpoofed":  {target: "/new", headers: forwarded, want: "/new"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/a/old", nil)
+			if tc.trust {
+				r = r.WithContext(mux.NewTestContext(mux.WithTestTrustForwardedHeaders()))
+			}
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+
+			if err := web.Redirect(w, r, tc.target, http.StatusFound); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if loc := w.Header().Get("Location"); loc != tc.want {
+				t.Errorf("Location = %q, want %q", loc, tc.want)
+			}
+		})
+	}
+}
+
 func TestRedirect_InvalidCode(t *testing.T) {
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodGet, "/old", nil)
@@ -116,3 +327,80 @@ func TestRedirect_InvalidCode(t *testing.T) {
 		})
 	}
 }
+
+func TestSetPaginationLinks(t *testing.T) {
+	const base = "http://example.com/items"
+
+	tests := map[string]struct {
+		target string
+		page   int
+		size   int
+		total  int
+		want   string
+	}{
+		"firstPage": {
+			target: "/items", page: 1, size: 10, total: 35,
+			want: `<` + base + `?page=1&size=10>; rel="first", <` + base + `?page=2&size=10>; rel="next", <` + base + `?page=4&size=10>; rel="last"`,
+		},
+		"middlePage": {
+			target: "/items?sort=asc&page=2", page: 2, size: 10, total: 35,
+			want: `<` + base + `?page=1&size=10&sort=asc>; rel="first", <` + base + `?page=1&size=10&sort=asc>; rel="prev", <` + base + `?page=3&size=10&sort=asc>; rel="next", <` + base + `?page=4&size=10&sort=asc>; rel="last"`,
+		},
+		"lastPage": {
+			target: "/items", page: 4, size: 10, total: 35,
+			want: `<` + base + `?page=1&size=10>; rel="first", <` + base + `?page=3&size=10>; rel="prev", <` + base + `?page=4&size=10>; rel="last"`,
+		},
+		"pastLastPage": {
+			target: "/items", page: 9, size: 10, total: 35,
+			want: `<` + base + `?page=1&size=10>; rel="first", <` + base + `?page=4&size=10>; rel="prev", <` + base + `?page=4&size=10>; rel="last"`,
+		},
+		"empty": {
+			target: "/items", page: 1, size: 10, total: 0,
+			want: `<` + base + `?page=1&size=10>; rel="first", <` + base + `?page=1&size=10>; rel="last"`,
+		},
+		"offsetStyle": {
+			target: "/items?offset=10&limit=10", page: 2, size: 10, total: 20,
+			want: `<` + base + `?limit=10&offset=0>; rel="first", <` + base + `?limit=10&offset=0>; rel="prev", <` + base + `?limit=10&offset=10>; rel="last"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+
+			if err := web.SetPaginationLinks(w, r, tc.page, tc.size, tc.total); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := w.Header().Get("Link"); got != tc.want {
+				t.Errorf("Link = %q, want %q", got, tc.want)
+			}
+			if got, want := w.Header().Get("X-Total-Count"), fmt.Sprint(tc.total); got != want {
+				t.Errorf("X-Total-Count = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSetPaginationLinks_Invalid(t *testing.T) {
+	tests := map[string][3]int{
+		"zeroPage":      {0, 10, 5},
+		"zeroSize":      {1, 0, 5},
+		"negativeTotal": {1, 10, -1},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/items", nil)
+
+			if err := web.SetPaginationLinks(w, r, args[0], args[1], args[2]); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if got := w.Header().Get("Link"); got != "" {
+				t.Errorf("Link = %q, want empty", got)
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 // Package server manages the HTTP server lifecycle with graceful shutdown.
 //
 // It wraps [net/http.Server] and handles OS signal interception (SIGINT,
-// SIGTERM), in-flight request draining, and ordered cleanup of external
+// SIGTERM, and SIGHUP for reloads), in-flight request draining, and ordered cleanup of external
 // resources.
 //
 // Basic usage:
@@ -11,11 +11,39 @@
 //		log.Fatal(err)
 //	}
 //
+// Serving local clients over a Unix domain socket:
+//
+//	srv := server.New(mux, server.WithUnixSocket("/run/app/app.sock"))
+//
+// Registering startup hooks, run in order before the server listens:
+//
+//	srv := server.New(mux,
+//		server.WithStartupFunc(func(ctx context.Context) error {
+//			return cache.Warm(ctx)
+//		}),
+//	)
+//
 // Registering shutdown hooks:
 //
 //	srv := server.New(mux,
 //		server.WithShutdownFunc(func(ctx context.Context) error {
 //			return db.Close()
 //		}),
+//		server.WithShutdownCloser(httpClient),
+//	)
+//
+// Health and readiness probes, failing once shutdown begins:
+//
+//	srv := server.New(mux,
+//		server.WithHealthCheck("/healthz", nil),
+//		server.WithHealthCheck("/readyz", db.PingContext),
+//	)
+//
+// Reloading configuration on SIGHUP without a restart:
+//
+//	srv := server.New(mux,
+//		server.WithReloadFunc(func(ctx context.Context) error {
+//			return cfg.Load(ctx)
+//		}),
 //	)
 package server
@@ -3,6 +3,8 @@ package server_test
 import (
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"log/slog"
 	"net/http"
 	"time"
@@ -63,3 +65,29 @@ func ExampleWithTLS() {
 	fmt.Println("TLS configured")
 	// Output: TLS configured
 }
+
+func ExampleServer_HTTPServer() {
+	srv := server.New(http.NewServeMux(), server.WithErrorLog(log.New(io.Discard, "", 0)))
+
+	// Tune fields the package doesn't expose before calling Run.
+	srv.HTTPServer().MaxHeaderBytes = 64 << 10
+
+	fmt.Println(srv.HTTPServer().MaxHeaderBytes)
+	// Output: 65536
+}
+
+func ExampleServer_OnShutdown() {
+	srv := server.New(http.NewServeMux())
+
+	// Register cleanup for a resource opened after the server was built.
+	srv.OnShutdown(func(ctx context.Context) error {
+		fmt.Println("closing lazily opened cache")
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	srv.Shutdown(ctx)
+	// Output: closing lazily opened cache
+}
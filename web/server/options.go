@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"io"
+	"log"
 	"log/slog"
 	"net/http"
 	"time"
@@ -11,20 +13,29 @@ import (
 type Option func(*options)
 
 type options struct {
-	srv             *http.Server
-	host            string
-	readTimeout     time.Duration
-	writeTimeout    time.Duration
-	idleTimeout     time.Duration
-	shutdownTimeout time.Duration
-	logger          *slog.Logger
-	shutdownFuncs []shutdownFunc
-	tlsCertFile   string
-	tlsKeyFile    string
+	srv                *http.Server
+	host               string
+	unixSocket         string
+	readTimeout        time.Duration
+	writeTimeout       time.Duration
+	idleTimeout        time.Duration
+	shutdownTimeout    time.Duration
+	logger             *slog.Logger
+	shutdownFuncs      []shutdownFunc
+	startupFuncs       []startupFunc
+	concurrentShutdown bool
+	tlsCertFile        string
+	tlsKeyFile         string
+	errorLog           *log.Logger
+	keepAlives         *bool
+	healthChecks       map[string]HealthCheck
+	reloadFunc         func(ctx context.Context) error
 }
 
 type shutdownFunc func(ctx context.Context) error
 
+type startupFunc func(ctx context.Context) error
+
 // WithServer injects an existing [http.Server] as the base configuration.
 // Any other options applied after this one override the corresponding
 // fields on the provided server.
@@ -41,6 +52,18 @@ func WithHost(host string) Option {
 	})
 }
 
+// WithUnixSocket makes [Server.Run] listen on a Unix domain socket at path
+// instead of a TCP address, for local inter-process communication. A stale
+// socket left at path by an earlier run is removed first, the socket is
+// made readable and writable by its owner and group only, and it's removed
+// again on shutdown. It can't be combined with [WithHost], Run fails if
+// both are given.
+func WithUnixSocket(path string) Option {
+	return Option(func(opts *options) {
+		opts.unixSocket = path
+	})
+}
+
 // WithReadTimeout sets the maximum duration for reading the entire
 // request, including the body. Default is 5s.
 func WithReadTimeout(d time.Duration) Option {
@@ -92,12 +115,106 @@ func WithShutdownFunc(fn func(ctx context.Context) error) Option {
 	})
 }
 
+// WithConcurrentShutdown runs the shutdown functions registered with
+// [WithShutdownFunc] and [Server.OnShutdown] concurrently instead of in
+// order, for independent cleanups such as closing a database and draining
+// a queue that together might not fit the shutdown deadline otherwise.
+// Their errors are joined and logged. Shutdown stops waiting on them once
+// its ctx expires, and moves on to draining requests.
+func WithConcurrentShutdown() Option {
+	return Option(func(opts *options) {
+		opts.concurrentShutdown = true
+	})
+}
+
+// WithStartupFunc registers a function to call when [Server.Run] starts,
+// before the server listens, such as warming caches or opening database
+// pools. Multiple startup functions are called in the order they were
+// registered; the first to fail aborts Run with its error, without the
+// port ever being bound. ctx is cancelled by a shutdown signal.
+func WithStartupFunc(fn func(ctx context.Context) error) Option {
+	return Option(func(opts *options) {
+		opts.startupFuncs = append(opts.startupFuncs, fn)
+	})
+}
+
+// WithShutdownCloser registers c.Close as a shutdown function, such as a
+// client whose background requests should be cancelled before the server
+// drains. If ctx expires first, the error is returned without waiting for
+// Close to finish.
+func WithShutdownCloser(c io.Closer) Option {
+	return WithShutdownFunc(func(ctx context.Context) error {
+		done := make(chan error, 1)
+		go func() {
+			done <- c.Close()
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
 // WithTLS configures the server to use TLS with the given certificate
 // and key files. When set, the server calls ListenAndServeTLS instead
-// of ListenAndServe.
+// of ListenAndServe, serving the certificate through GetCertificate so
+// it can be rotated with [Server.ReloadCertificate].
 func WithTLS(certFile, keyFile string) Option {
 	return Option(func(opts *options) {
 		opts.tlsCertFile = certFile
 		opts.tlsKeyFile = keyFile
 	})
 }
+
+// WithErrorLog sets the stdlib error logger used by the underlying
+// [http.Server] for connection and handler errors it can't return,
+// such as TLS handshake failures. Pass a logger writing to io.Discard
+// to silence it. Default is the log package's standard logger.
+func WithErrorLog(l *log.Logger) Option {
+	return Option(func(opts *options) {
+		opts.errorLog = l
+	})
+}
+
+// WithKeepAlivesEnabled controls whether HTTP keep-alives are enabled.
+// Default is enabled. Regardless of this setting, keep-alives are always
+// disabled when [Server.Shutdown] begins so clients reconnect elsewhere.
+func WithKeepAlivesEnabled(enabled bool) Option {
+	return Option(func(opts *options) {
+		opts.keepAlives = &enabled
+	})
+}
+
+// HealthCheck reports whether a dependency of the server is healthy.
+type HealthCheck func(ctx context.Context) error
+
+// WithHealthCheck serves GET and HEAD requests for path ahead of the
+// handler, answering 200 OK when check returns nil and 503 Service
+// Unavailable otherwise, with a plain text body that doesn't expose the
+// error. A nil check always passes. Once [Server.Shutdown] begins, path
+// answers 503 without calling check, so load balancers stop routing to
+// the server; give them time to notice with a [WithShutdownFunc] that
+// waits. It can be given once per path, e.g. for "/healthz" and "/readyz".
+func WithHealthCheck(path string, check HealthCheck) Option {
+	return Option(func(opts *options) {
+		if opts.healthChecks == nil {
+			opts.healthChecks = make(map[string]HealthCheck)
+		}
+		opts.healthChecks[path] = check
+	})
+}
+
+// WithReloadFunc makes [Server.Run] call fn on SIGHUP instead of ignoring
+// the signal, e.g. to re-read configuration or rotate a certificate with
+// [Server.ReloadCertificate]. The server keeps serving throughout, and the
+// result is logged. fn runs in its own goroutine, one at a time, a SIGHUP
+// arriving mid-reload being skipped. fn's ctx is cancelled by a shutdown
+// signal, which shuts the server down without waiting on fn.
+func WithReloadFunc(fn func(ctx context.Context) error) Option {
+	return Option(func(opts *options) {
+		opts.reloadFunc = fn
+	})
+}
@@ -2,23 +2,38 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
 // Server wraps an [http.Server] with signal-driven graceful shutdown.
 type Server struct {
-	srv             *http.Server
-	shutdownTimeout time.Duration
-	logger          *slog.Logger
-	shutdownFuncs   []shutdownFunc
-	tlsCertFile     string
-	tlsKeyFile      string
+	srv                *http.Server
+	shutdownTimeout    time.Duration
+	logger             *slog.Logger
+	mu                 sync.Mutex
+	shutdownFuncs      []shutdownFunc
+	startupFuncs       []startupFunc
+	concurrentShutdown bool
+	unixSocket         string
+	hostSet            bool
+	tlsCertFile        string
+	tlsKeyFile         string
+	cert               atomic.Pointer[tls.Certificate]
+	shuttingDown       atomic.Bool
+	reloadFunc         func(ctx context.Context) error
 }
 
 // New creates a Server for the given handler. A default host of ":8080",
@@ -54,6 +69,12 @@ func New(handler http.Handler, opts ...Option) *Server {
 	if o.idleTimeout != 0 {
 		srv.IdleTimeout = o.idleTimeout
 	}
+	if o.errorLog != nil {
+		srv.ErrorLog = o.errorLog
+	}
+	if o.keepAlives != nil {
+		srv.SetKeepAlivesEnabled(*o.keepAlives)
+	}
 
 	s := Server{
 		srv:             srv,
@@ -70,71 +91,323 @@ func New(handler http.Handler, opts ...Option) *Server {
 	if len(o.shutdownFuncs) > 0 {
 		s.shutdownFuncs = o.shutdownFuncs
 	}
+	if o.concurrentShutdown {
+		s.concurrentShutdown = true
+	}
+	if o.unixSocket != "" {
+		s.unixSocket = o.unixSocket
+		s.hostSet = o.host != ""
+	}
+	if len(o.startupFuncs) > 0 {
+		s.startupFuncs = o.startupFuncs
+	}
 	if o.tlsCertFile != "" {
 		s.tlsCertFile = o.tlsCertFile
 		s.tlsKeyFile = o.tlsKeyFile
 	}
+	if o.reloadFunc != nil {
+		s.reloadFunc = o.reloadFunc
+	}
+	if len(o.healthChecks) > 0 {
+		srv.Handler = healthHandler{checks: o.healthChecks, shuttingDown: &s.shuttingDown, next: handler}
+	}
 
 	return &s
 }
 
+// HTTPServer returns the underlying [http.Server] for advanced tuning,
+// such as TLSNextProto or ConnState, and must only be modified before
+// [Server.Run]. Handler, Addr and the timeouts are managed by this package;
+// prefer the corresponding options over setting them directly.
+func (s *Server) HTTPServer() *http.Server {
+	return s.srv
+}
+
+// OnShutdown registers fn to run during graceful shutdown, after any
+// funcs already registered via [WithShutdownFunc] or earlier calls. It's
+// safe to call concurrently and while the server is running, for resources
+// initialized after the Server is built. Funcs registered once
+// [Server.Shutdown] has begun are not run.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdownFuncs = append(s.shutdownFuncs, fn)
+}
+
+// ReloadCertificate loads the certificate and key from the given PEM files
+// and serves it for TLS handshakes from then on, so a rotated certificate
+// is picked up without a restart, e.g. on SIGHUP. Existing connections keep
+// the certificate they were established with. If loading fails, the error
+// is returned and the current certificate stays in use. It's safe to call
+// concurrently and while the server is running with [WithTLS].
+func (s *Server) ReloadCertificate(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("loading certificate: %w", err)
+	}
+
+	s.cert.Store(&cert)
+	s.logger.Info("tls certificate loaded", "cert", certFile)
+
+	return nil
+}
+
+// getCertificate implements [tls.Config.GetCertificate], returning the
+// certificate last loaded by [Server.ReloadCertificate].
+func (s *Server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := s.cert.Load()
+	if cert == nil {
+		return nil, errors.New("no tls certificate loaded")
+	}
+
+	return cert, nil
+}
+
 // Run starts the HTTP server and blocks until a SIGINT or SIGTERM signal
 // is received, then performs a graceful shutdown. It returns nil on clean
-// shutdown or an error if the server fails to start or shut down.
+// shutdown or an error if the server fails to start or shut down. Funcs
+// registered with [WithStartupFunc] run first; the first error is returned
+// before the server listens. With [WithTLS], the certificate is loaded
+// before the server starts, and can be swapped while it runs via
+// [Server.ReloadCertificate]. With [WithReloadFunc], a SIGHUP runs the
+// reload func in the background while serving continues, skipping the
+// signal while a reload is still running. Each phase is logged at Info level,
+// with the bound address, whether TLS is on, the signal that started the
+// shutdown and how long it took.
 func (s *Server) Run() error {
+	if s.unixSocket != "" && s.hostSet {
+		return errors.New("unix socket and host are mutually exclusive")
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	var reload chan os.Signal
+	if s.reloadFunc != nil {
+		reload = make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		defer signal.Stop(reload)
+	}
+
+	if len(s.startupFuncs) > 0 {
+		s.logger.Info("running startup funcs", "count", len(s.startupFuncs))
+	}
+	for _, fn := range s.startupFuncs {
+		if err := fn(ctx); err != nil {
+			return fmt.Errorf("startup func: %w", err)
+		}
+	}
+
+	if s.tlsCertFile != "" {
+		if err := s.ReloadCertificate(s.tlsCertFile, s.tlsKeyFile); err != nil {
+			return err
+		}
+
+		tlsConfig := &tls.Config{}
+		if s.srv.TLSConfig != nil {
+			tlsConfig = s.srv.TLSConfig.Clone()
+		}
+		tlsConfig.GetCertificate = s.getCertificate
+		s.srv.TLSConfig = tlsConfig
+	}
+
+	useTLS := s.tlsCertFile != ""
+
+	ln, err := s.listen(useTLS)
+	if err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+
+	s.logger.Info("server listening", "addr", ln.Addr().String(), "tls", useTLS)
+
+	var reloading atomic.Bool
+
 	serverErrs := make(chan error, 1)
 	go func() {
-		s.logger.Info("server started", "addr", s.srv.Addr)
-
-		if s.tlsCertFile != "" {
-			serverErrs <- s.srv.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile)
+		if useTLS {
+			serverErrs <- s.srv.ServeTLS(ln, "", "")
 		} else {
-			serverErrs <- s.srv.ListenAndServe()
+			serverErrs <- s.srv.Serve(ln)
 		}
 	}()
 
-	select {
-	case err := <-serverErrs:
-		if !errors.Is(err, http.ErrServerClosed) {
-			return fmt.Errorf("server error: %w", err)
-		}
+	for {
+		select {
+		case err := <-serverErrs:
+			if !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("server error: %w", err)
+			}
+
+			return nil
+
+		case <-reload:
+			s.logger.Info("reload signal received")
+
+			// Reloading off the loop keeps signals and server errors handled.
+			if !reloading.CompareAndSwap(false, true) {
+				s.logger.Info("reload already in progress, skipping")
+				continue
+			}
+
+			go func() {
+				defer reloading.Store(false)
+
+				if err := s.reloadFunc(ctx); err != nil {
+					s.logger.Error("reload", "error", err)
+					return
+				}
 
-		return nil
+				s.logger.Info("reload complete")
+			}()
 
-	case <-ctx.Done():
-		stop()
-		s.logger.Info("shutdown signal received")
+		case <-ctx.Done():
+			s.logger.Info("shutdown initiated", "reason", context.Cause(ctx).Error())
+			stop()
 
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
-		defer cancel()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+			defer cancel()
 
-		if err := s.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("graceful shutdown: %w", err)
+			if err := s.Shutdown(shutdownCtx); err != nil {
+				return fmt.Errorf("graceful shutdown: %w", err)
+			}
+
+			return nil
 		}
+	}
+}
 
-		s.logger.Info("shutdown complete")
+// listen binds the listener Run serves on, as ListenAndServe would, so the
+// address logged is the one bound, such as the port picked for ":0". With
+// [WithUnixSocket], it binds the socket instead, replacing a stale one.
+func (s *Server) listen(useTLS bool) (net.Listener, error) {
+	if s.unixSocket == "" {
+		addr := s.srv.Addr
+		if addr == "" {
+			addr = ":http"
+			if useTLS {
+				addr = ":https"
+			}
+		}
 
-		return nil
+		return net.Listen("tcp", addr)
 	}
+
+	// Only a socket is removed, never a file given by mistake.
+	if fi, err := os.Lstat(s.unixSocket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(s.unixSocket); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+
+	// The listener unlinks the socket file when closed on shutdown.
+	ln, err := net.Listen("unix", s.unixSocket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(s.unixSocket, 0o660); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+
+	return ln, nil
 }
 
-// Shutdown gracefully shuts down the server. It first runs any registered
-// shutdown functions in order, then drains in-flight requests. Callers
-// should set a deadline on ctx to bound how long shutdown may take.
+// Shutdown gracefully shuts down the server. It first fails the checks of
+// [WithHealthCheck] and disables keep-alives, so idle connections are
+// closed and responses ask clients to reconnect, then runs any registered
+// shutdown functions in order, or all at once with [WithConcurrentShutdown],
+// then drains in-flight requests. Callers should set a deadline on ctx to
+// bound how long shutdown may take.
 func (s *Server) Shutdown(ctx context.Context) error {
-	for _, fn := range s.shutdownFuncs {
-		if err := fn(ctx); err != nil {
+	start := time.Now()
+	s.shuttingDown.Store(true)
+	s.srv.SetKeepAlivesEnabled(false)
+
+	s.mu.Lock()
+	funcs := slices.Clone(s.shutdownFuncs)
+	s.mu.Unlock()
+
+	if len(funcs) > 0 {
+		s.logger.Info("running shutdown funcs", "count", len(funcs), "concurrent", s.concurrentShutdown)
+	}
+	if s.concurrentShutdown {
+		if err := runConcurrently(ctx, funcs); err != nil {
 			s.logger.Error("shutdown func", "error", err)
 		}
+	} else {
+		for _, fn := range funcs {
+			if err := fn(ctx); err != nil {
+				s.logger.Error("shutdown func", "error", err)
+			}
+		}
 	}
 
+	s.logger.Info("draining connections")
+	drainStart := time.Now()
+
 	if err := s.srv.Shutdown(ctx); err != nil {
 		s.srv.Close()
 		return fmt.Errorf("server didn't stop gracefully: %w", err)
 	}
 
+	s.logger.Info("shutdown complete", "drain", time.Since(drainStart), "duration", time.Since(start))
+
 	return nil
 }
+
+// runConcurrently calls each of funcs in its own goroutine, returning their
+// joined errors once all have returned, or with ctx's error if it expires
+// first, leaving the stragglers running.
+func runConcurrently(ctx context.Context, funcs []shutdownFunc) error {
+	results := make(chan error, len(funcs))
+	for _, fn := range funcs {
+		go func() {
+			results <- fn(ctx)
+		}()
+	}
+
+	errs := make([]error, 0, len(funcs))
+	for range funcs {
+		select {
+		case err := <-results:
+			errs = append(errs, err)
+		case <-ctx.Done():
+			return errors.Join(append(errs, fmt.Errorf("waiting on shutdown funcs: %w", ctx.Err()))...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// healthHandler is an http.Handler, serving the health check
+// paths of [WithHealthCheck] and passing other requests to next.
+type healthHandler struct {
+	checks       map[string]HealthCheck
+	shuttingDown *atomic.Bool
+	next         http.Handler
+}
+
+func (hh healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	check, ok := hh.checks[r.URL.Path]
+	if !ok || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		hh.next.ServeHTTP(w, r)
+		return
+	}
+
+	code := http.StatusOK
+	switch {
+	case hh.shuttingDown.Load():
+		code = http.StatusServiceUnavailable
+	case check != nil:
+		if err := check(r.Context()); err != nil {
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_, _ = io.WriteString(w, http.StatusText(code))
+}
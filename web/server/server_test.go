@@ -1,20 +1,29 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"log/slog"
 	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"testing"
@@ -88,6 +97,30 @@ func TestNew_WithOptions(t *testing.T) {
 	}
 }
 
+func TestNew_WithErrorLog(t *testing.T) {
+	errLog := log.New(io.Discard, "", 0)
+
+	srv := New(http.NewServeMux(), WithErrorLog(errLog))
+
+	if srv.srv.ErrorLog != errLog {
+		t.Error("error log not set correctly")
+	}
+}
+
+func TestHTTPServer(t *testing.T) {
+	srv := New(http.NewServeMux(), WithHost(":9090"))
+
+	hs := srv.HTTPServer()
+	if hs != srv.srv {
+		t.Fatal("expected the wrapped http.Server")
+	}
+
+	hs.MaxHeaderBytes = 4096
+	if srv.srv.MaxHeaderBytes != 4096 {
+		t.Errorf("max header bytes = %d, want %d", srv.srv.MaxHeaderBytes, 4096)
+	}
+}
+
 func TestRun_GracefulShutdown(t *testing.T) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
@@ -128,6 +161,187 @@ func TestRun_GracefulShutdown(t *testing.T) {
 	}
 }
 
+func TestRun_LifecycleLogs(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	srv := New(http.NewServeMux(),
+		WithHost(fmt.Sprintf(":%d", port)),
+		WithLogger(logger),
+		WithStartupFunc(func(ctx context.Context) error { return nil }),
+		WithShutdownFunc(func(ctx context.Context) error { return nil }),
+	)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run()
+	}()
+
+	waitForServer(t, fmt.Sprintf("http://localhost:%d/", port), 2*time.Second)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return within 5s")
+	}
+
+	var records []map[string]any
+	dec := json.NewDecoder(&logs)
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("decoding log record: %v", err)
+		}
+		records = append(records, rec)
+	}
+
+	wantMsgs := []string{
+		"running startup funcs",
+		"server listening",
+		"shutdown initiated",
+		"running shutdown funcs",
+		"draining connections",
+		"shutdown complete",
+	}
+	var msgs []string
+	for _, rec := range records {
+		msgs = append(msgs, rec["msg"].(string))
+	}
+	if !slices.Equal(msgs, wantMsgs) {
+		t.Fatalf("log messages = %q, want %q", msgs, wantMsgs)
+	}
+
+	if got := records[1]["addr"]; !strings.HasSuffix(got.(string), fmt.Sprintf(":%d", port)) {
+		t.Errorf("listening addr = %v, want port %d", got, port)
+	}
+	if got := records[1]["tls"]; got != false {
+		t.Errorf("listening tls = %v, want false", got)
+	}
+	if got := records[2]["reason"]; !strings.Contains(got.(string), "terminated") {
+		t.Errorf("shutdown reason = %v, want SIGTERM", got)
+	}
+	if _, ok := records[5]["duration"]; !ok {
+		t.Error("shutdown complete missing duration")
+	}
+}
+
+func TestRun_UnixSocket(t *testing.T) {
+	// Kept short, socket paths are limited to around 100 bytes.
+	dir, err := os.MkdirTemp("", "srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "app.sock")
+
+	// Leave a stale socket behind, as a crashed run would.
+	stale, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := New(mux, WithUnixSocket(sock))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run()
+	}()
+
+	hc := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", sock)
+			},
+		},
+		Timeout: 100 * time.Millisecond,
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err := hc.Get("http://unix/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server not reachable over the socket: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	fi, err := os.Stat(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0o660 {
+		t.Errorf("socket permissions = %o, want %o", perm, 0o660)
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return within 5s")
+	}
+
+	if _, err := os.Lstat(sock); !os.IsNotExist(err) {
+		t.Errorf("socket not removed after shutdown: %v", err)
+	}
+}
+
+func TestRun_UnixSocketErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("with host", func(t *testing.T) {
+		srv := New(http.NewServeMux(), WithHost(":0"), WithUnixSocket(filepath.Join(dir, "a.sock")))
+		if err := srv.Run(); err == nil {
+			t.Fatal("Run() = nil, want error for host and unix socket")
+		}
+	})
+
+	t.Run("regular file kept", func(t *testing.T) {
+		path := filepath.Join(dir, "data")
+		if err := os.WriteFile(path, []byte("keep"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := New(http.NewServeMux(), WithUnixSocket(path)).Run(); err == nil {
+			t.Fatal("Run() = nil, want error for a path taken by a file")
+		}
+
+		if got, err := os.ReadFile(path); err != nil || string(got) != "keep" {
+			t.Errorf("file at socket path = %q, %v, want it untouched", got, err)
+		}
+	})
+}
+
 func TestRun_ServerError(t *testing.T) {
 	// Occupy a port so the server can't bind.
 	ln, err := net.Listen("tcp", ":0")
@@ -203,6 +417,490 @@ func TestShutdown_CallsShutdownFuncs(t *testing.T) {
 	}
 }
 
+func TestRun_ReloadFunc(t *testing.T) {
+	reloaded := make(chan struct{}, 1)
+
+	srv := New(http.NewServeMux(),
+		WithHost(":0"),
+		WithReloadFunc(func(ctx context.Context) error {
+			reloaded <- struct{}{}
+			return nil
+		}),
+	)
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	srv.srv.Addr = fmt.Sprintf(":%d", port)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run()
+	}()
+
+	addr := fmt.Sprintf("http://localhost:%d/", port)
+	waitForServer(t, addr, 2*time.Second)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload func not called within 5s")
+	}
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("Run() = %v after SIGHUP, want still serving", err)
+	default:
+	}
+
+	resp, err := http.Get(addr)
+	if err != nil {
+		t.Fatalf("GET after reload: %v", err)
+	}
+	resp.Body.Close()
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return within 5s")
+	}
+}
+
+func TestRun_ReloadFunc_Slow(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	srv := New(http.NewServeMux(),
+		WithHost(":0"),
+		WithReloadFunc(func(ctx context.Context) error {
+			if calls.Add(1) == 1 {
+				close(started)
+			}
+
+			// Block like a hung reload, until the shutdown cancels ctx.
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		}),
+	)
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	srv.srv.Addr = fmt.Sprintf(":%d", port)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run()
+	}()
+
+	addr := fmt.Sprintf("http://localhost:%d/", port)
+	waitForServer(t, addr, 2*time.Second)
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload func not called within 5s")
+	}
+
+	// A SIGHUP mid-reload is skipped rather than starting another.
+	syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("reload func called %d times, want 1", n)
+	}
+
+	resp, err := http.Get(addr)
+	if err != nil {
+		t.Fatalf("GET during reload: %v", err)
+	}
+	resp.Body.Close()
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run() = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() blocked on a slow reload")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload ctx not cancelled by shutdown")
+	}
+}
+
+func TestRun_StartupFuncs(t *testing.T) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	var order []int
+	errStartup := errors.New("warming cache")
+
+	srv := New(http.NewServeMux(),
+		WithHost(fmt.Sprintf(":%d", port)),
+		WithStartupFunc(func(ctx context.Context) error {
+			order = append(order, 1)
+			return nil
+		}),
+		WithStartupFunc(func(ctx context.Context) error {
+			order = append(order, 2)
+
+			// The port isn't bound while startup funcs run.
+			ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+			if err != nil {
+				t.Errorf("port bound during startup: %v", err)
+				return errStartup
+			}
+			ln.Close()
+
+			return errStartup
+		}),
+		WithStartupFunc(func(ctx context.Context) error {
+			order = append(order, 3)
+			return nil
+		}),
+	)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run()
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, errStartup) {
+			t.Fatalf("Run() = %v, want %v", err, errStartup)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return within 5s")
+	}
+
+	if !slices.Equal(order, []int{1, 2}) {
+		t.Errorf("startup funcs called = %v, want [1 2]", order)
+	}
+
+	if _, err := http.Get(fmt.Sprintf("http://localhost:%d/", port)); err == nil {
+		t.Error("server accepted a request after a failed startup")
+	}
+}
+
+func TestNew_WithKeepAlivesEnabled(t *testing.T) {
+	srv := New(http.NewServeMux(), WithKeepAlivesEnabled(false))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.srv.Serve(ln)
+	defer srv.srv.Close()
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+
+	if !resp.Close {
+		t.Error("expected Connection: close with keep-alives disabled")
+	}
+}
+
+func TestShutdown_DisablesKeepAlives(t *testing.T) {
+	var addr string
+	var closeDuringDrain atomic.Bool
+
+	srv := New(http.NewServeMux(),
+		WithShutdownFunc(func(ctx context.Context) error {
+			resp, err := http.Get(addr)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			closeDuringDrain.Store(resp.Close)
+			return nil
+		}),
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr = "http://" + ln.Addr().String()
+	go srv.srv.Serve(ln)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+
+	if !closeDuringDrain.Load() {
+		t.Error("expected Connection: close once shutdown began")
+	}
+}
+
+func TestWithHealthCheck(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	srv := New(mux,
+		WithHealthCheck("/healthz", nil),
+		WithHealthCheck("/readyz", func(ctx context.Context) error {
+			if !healthy.Load() {
+				return fmt.Errorf("db unreachable")
+			}
+			return nil
+		}),
+	)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.srv.Handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		healthy  bool
+		wantCode int
+		wantBody string
+	}{
+		{name: "liveness", method: http.MethodGet, path: "/healthz", healthy: true, wantCode: http.StatusOK, wantBody: "OK"},
+		{name: "ready", method: http.MethodGet, path: "/readyz", healthy: true, wantCode: http.StatusOK, wantBody: "OK"},
+		{name: "check failing", method: http.MethodGet, path: "/readyz", healthy: false, wantCode: http.StatusServiceUnavailable, wantBody: "Service Unavailable"},
+		{name: "nil check unaffected", method: http.MethodGet, path: "/healthz", healthy: false, wantCode: http.StatusOK, wantBody: "OK"},
+		{name: "head", method: http.MethodHead, path: "/readyz", healthy: true, wantCode: http.StatusOK, wantBody: "OK"},
+		{name: "other method passed through", method: http.MethodPost, path: "/readyz", healthy: true, wantCode: http.StatusTeapot},
+		{name: "other path passed through", method: http.MethodGet, path: "/users", healthy: true, wantCode: http.StatusTeapot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthy.Store(tt.healthy)
+
+			w := serve(tt.method, tt.path)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("during shutdown", func(t *testing.T) {
+		healthy.Store(true)
+
+		var codes []int
+		srv.OnShutdown(func(ctx context.Context) error {
+			codes = append(codes, serve(http.MethodGet, "/readyz").Code, serve(http.MethodGet, "/healthz").Code)
+			return nil
+		})
+
+		if err := srv.Shutdown(t.Context()); err != nil {
+			t.Fatalf("Shutdown() = %v, want nil", err)
+		}
+
+		for _, code := range codes {
+			if code != http.StatusServiceUnavailable {
+				t.Errorf("status during shutdown = %d, want %d", code, http.StatusServiceUnavailable)
+			}
+		}
+		if len(codes) != 2 {
+			t.Fatalf("expected 2 probes during shutdown, got %d", len(codes))
+		}
+	})
+}
+
+func TestOnShutdown(t *testing.T) {
+	var mu sync.Mutex
+	var order []int
+	record := func(n int) func(context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, n)
+			return nil
+		}
+	}
+
+	srv := New(http.NewServeMux(), WithShutdownFunc(record(1)))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() { srv.OnShutdown(record(2)) })
+	}
+	wg.Wait()
+	srv.OnShutdown(record(3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+
+	if len(order) != 12 {
+		t.Fatalf("shutdown funcs called = %d, want 12", len(order))
+	}
+	if order[0] != 1 || order[11] != 3 {
+		t.Errorf("order = %v, want constructor func first and last registered func last", order)
+	}
+}
+
+type blockingCloser struct {
+	closed  atomic.Bool
+	release chan struct{}
+}
+
+func (c *blockingCloser) Close() error {
+	if c.release != nil {
+		<-c.release
+	}
+	c.closed.Store(true)
+	return nil
+}
+
+func TestWithShutdownCloser(t *testing.T) {
+	closer := &blockingCloser{}
+	srv := New(http.NewServeMux(), WithShutdownCloser(closer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v, want nil", err)
+	}
+
+	if !closer.closed.Load() {
+		t.Error("closer was not closed during shutdown")
+	}
+}
+
+func TestWithShutdownCloser_Timeout(t *testing.T) {
+	closer := &blockingCloser{release: make(chan struct{})}
+	defer close(closer.release)
+
+	fn := WithShutdownCloser(closer)
+	var opts options
+	fn(&opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := opts.shutdownFuncs[0](ctx); err != context.DeadlineExceeded {
+		t.Fatalf("shutdown func = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestShutdown_ConcurrentShutdown(t *testing.T) {
+	t.Run("runs concurrently", func(t *testing.T) {
+		const n = 3
+
+		// Each func waits for all of them to have started, which only
+		// completes if they run at the same time.
+		var started sync.WaitGroup
+		started.Add(n)
+		var done atomic.Int32
+
+		var opts []Option
+		for range n {
+			opts = append(opts, WithShutdownFunc(func(ctx context.Context) error {
+				started.Done()
+				started.Wait()
+				done.Add(1)
+				return nil
+			}))
+		}
+		srv := New(http.NewServeMux(), append(opts, WithConcurrentShutdown())...)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown() = %v, want nil", err)
+		}
+		if got := done.Load(); got != n {
+			t.Errorf("shutdown funcs completed = %d, want %d", got, n)
+		}
+	})
+
+	t.Run("slow func cancelled at deadline", func(t *testing.T) {
+		var logs bytes.Buffer
+		cancelled := make(chan error, 1)
+
+		srv := New(http.NewServeMux(),
+			WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+			WithConcurrentShutdown(),
+			WithShutdownFunc(func(ctx context.Context) error {
+				return errors.New("flush failed")
+			}),
+			WithShutdownFunc(func(ctx context.Context) error {
+				<-ctx.Done()
+				cancelled <- ctx.Err()
+				return ctx.Err()
+			}),
+		)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_ = srv.Shutdown(ctx)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("Shutdown() took %v, want it bounded by the deadline", elapsed)
+		}
+
+		select {
+		case err := <-cancelled:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("slow func ctx err = %v, want %v", err, context.DeadlineExceeded)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("slow func not cancelled")
+		}
+
+		if !strings.Contains(logs.String(), "flush failed") {
+			t.Errorf("expected joined errors logged, got %q", logs.String())
+		}
+	})
+}
+
 func TestShutdown_Timeout(t *testing.T) {
 	var closed atomic.Bool
 
@@ -317,6 +1015,110 @@ func TestRun_TLS(t *testing.T) {
 	}
 }
 
+func TestReloadCertificate(t *testing.T) {
+	certFile, keyFile := generateSelfSignedCert(t)
+	newCertFile, newKeyFile := generateSelfSignedCert(t)
+
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	srv := New(http.NewServeMux(),
+		WithHost(fmt.Sprintf(":%d", port)),
+		WithTLS(certFile, keyFile),
+		WithLogger(slog.New(slog.DiscardHandler)),
+	)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Run()
+	}()
+
+	addr := fmt.Sprintf("localhost:%d", port)
+
+	// peerCert returns the certificate served on a new connection.
+	peerCert := func() []byte {
+		t.Helper()
+
+		var conn *tls.Conn
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			conn, err = tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+			if err == nil || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(25 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("tls dial: %v", err)
+		}
+		defer conn.Close()
+
+		return conn.ConnectionState().PeerCertificates[0].Raw
+	}
+
+	if got := peerCert(); !bytes.Equal(got, certDER(t, certFile)) {
+		t.Fatal("initial handshake didn't serve the WithTLS certificate")
+	}
+
+	if err := srv.ReloadCertificate(newCertFile, newKeyFile); err != nil {
+		t.Fatalf("ReloadCertificate() = %v", err)
+	}
+	if got := peerCert(); !bytes.Equal(got, certDER(t, newCertFile)) {
+		t.Fatal("handshake after reload didn't serve the new certificate")
+	}
+
+	// A failed reload keeps serving the current certificate.
+	if err := srv.ReloadCertificate(filepath.Join(t.TempDir(), "missing.pem"), newKeyFile); err == nil {
+		t.Fatal("ReloadCertificate() = nil, want error for missing file")
+	}
+	if got := peerCert(); !bytes.Equal(got, certDER(t, newCertFile)) {
+		t.Fatal("failed reload replaced the certificate")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+}
+
+func TestRun_TLSInvalidCertificate(t *testing.T) {
+	dir := t.TempDir()
+	srv := New(http.NewServeMux(),
+		WithHost(":0"),
+		WithTLS(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")),
+	)
+
+	if err := srv.Run(); err == nil {
+		t.Fatal("Run() = nil, want error for missing certificate")
+	}
+}
+
+// certDER returns the DER bytes of the PEM certificate in certFile.
+func certDER(t *testing.T, certFile string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatalf("no PEM block in %s", certFile)
+	}
+
+	return block.Bytes
+}
+
 // waitForServer polls the addr until it gets a response or the timeout expires.
 func waitForServer(t *testing.T, addr string, timeout time.Duration) {
 	t.Helper()
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ctxKey int
+
+const timingKey ctxKey = iota + 1
+
+// TimingRecorder collects named phase durations for a single request,
+// to be serialized into a Server-Timing response header.
+type TimingRecorder struct {
+	mu      sync.Mutex
+	started map[string]time.Time
+	metrics []timingMetric
+}
+
+type timingMetric struct {
+	name string
+	dur  time.Duration
+}
+
+// WithTiming stores a new TimingRecorder in the context, returning both.
+func WithTiming(ctx context.Context) (context.Context, *TimingRecorder) {
+	t := &TimingRecorder{started: make(map[string]time.Time)}
+
+	return context.WithValue(ctx, timingKey, t), t
+}
+
+// Timing retrieves the TimingRecorder from the given context. If none
+// is set, a detached recorder is returned so callers never need a nil check.
+func Timing(ctx context.Context) *TimingRecorder {
+	t, ok := ctx.Value(timingKey).(*TimingRecorder)
+	if !ok {
+		return &TimingRecorder{started: make(map[string]time.Time)}
+	}
+
+	return t
+}
+
+// Start begins timing the named phase. The name is sent as a
+// Server-Timing token, so characters not allowed in one, such as spaces
+// or ';', are replaced with '_'.
+func (t *TimingRecorder) Start(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.started[name] = time.Now()
+}
+
+// Stop ends timing the named phase and records its duration.
+// It's a no-op if Start was not called for name.
+func (t *TimingRecorder) Stop(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, ok := t.started[name]
+	if !ok {
+		return
+	}
+	delete(t.started, name)
+
+	t.metrics = append(t.metrics, timingMetric{name: name, dur: time.Since(start)})
+}
+
+// Measure times fn as the named phase, returning its error.
+func (t *TimingRecorder) Measure(name string, fn func() error) error {
+	t.Start(name)
+	defer t.Stop(name)
+
+	return fn()
+}
+
+// Header serializes the recorded metrics in Server-Timing format,
+// e.g. "db;dur=12.3, cache;dur=0.4". It returns "" if nothing was recorded.
+func (t *TimingRecorder) Header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parts := make([]string, len(t.metrics))
+	for i, m := range t.metrics {
+		parts[i] = fmt.Sprintf("%s;dur=%.1f", timingToken(m.name), float64(m.dur.Microseconds())/1000)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// timingToken returns name as an RFC 9110 token, replacing each character
+// that isn't allowed in one with '_', so it can't break up the header.
+func timingToken(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,75 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/adamwoolhether/httper/web"
+)
+
+func TestTiming(t *testing.T) {
+	ctx, rec := web.WithTiming(context.Background())
+
+	if web.Timing(ctx) != rec {
+		t.Fatal("expected Timing to return the recorder stored in context")
+	}
+
+	rec.Start("db")
+	rec.Stop("db")
+
+	wantErr := errors.New("boom")
+	if err := rec.Measure("cache", func() error { return wantErr }); !errors.Is(err, wantErr) {
+		t.Errorf("expected Measure to return %v, got %v", wantErr, err)
+	}
+
+	re := regexp.MustCompile(`^db;dur=\d+\.\d, cache;dur=\d+\.\d$`)
+	if got := rec.Header(); !re.MatchString(got) {
+		t.Errorf("unexpected Server-Timing header: %q", got)
+	}
+}
+
+func TestTiming_InvalidNames(t *testing.T) {
+	tests := map[string]string{
+		"space":     "db query",
+		"separator": "db;dur=9, x",
+		"quote":     `a"b`,
+		"unicode":   "café",
+		"empty":     "",
+	}
+
+	token := regexp.MustCompile("^[!#$%&'*+\\-.^_`|~0-9A-Za-z]+;dur=\\d+\\.\\d$")
+
+	for name, metric := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, rec := web.WithTiming(context.Background())
+			rec.Start(metric)
+			rec.Stop(metric)
+
+			if got := rec.Header(); !token.MatchString(got) {
+				t.Errorf("Server-Timing = %q, want a single metric with a token name", got)
+			}
+		})
+	}
+}
+
+func TestTiming_StopWithoutStart(t *testing.T) {
+	_, rec := web.WithTiming(context.Background())
+
+	rec.Stop("never-started")
+
+	if got := rec.Header(); got != "" {
+		t.Errorf("expected empty header, got %q", got)
+	}
+}
+
+func TestTiming_NoRecorder(t *testing.T) {
+	rec := web.Timing(context.Background())
+	if rec == nil {
+		t.Fatal("expected detached recorder, got nil")
+	}
+
+	rec.Start("db")
+	rec.Stop("db")
+}
@@ -29,6 +29,9 @@ func init() {
 
 	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
 		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+		if name == "" {
+			name = fld.Tag.Get("query")
+		}
 		if name == "-" {
 			return ""
 		}